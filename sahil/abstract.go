@@ -1,16 +1,54 @@
 package sahil
 
 import (
+	"io"
 	"sync"
+	"time"
+
+	"github.com/Nyeogmi/sahil-go/sahil/internal/observe"
 )
 
-// fetch is the internal interface implemented by Paginated-compatible data
-// sources. It can return as many results as it wants, but must return at least
-// atLeast results so long as the  data sourec is not completed.
-type fetch[T any] interface {
+// Source is the interface implemented by Paginated-compatible data sources.
+// Every constructor and stage in this package is a Source underneath: use
+// FromSource to turn your own into a Paginated.
+//
+// Fetch can return as many results as it wants, but must return at least
+// atLeast results so long as the data source is not completed. Returning fewer
+// than atLeast results (or an error) signals that the data source is done.
+//
+// In exchange, the Paginated returned by FromSource guarantees that:
+//
+//   - Fetch is never called concurrently, so a Source needs no locking.
+//   - atLeast is always at least 1.
+//   - Fetch is never called again after it signals that it is done. The
+//     Paginated drops its reference to the Source at that point.
+//   - Any results beyond what the caller of the Paginated asked for are
+//     buffered and handed out on later calls, so a Source is free to return
+//     whatever batch size is natural for it.
+//   - An error is reproduced on every later call, and the results returned
+//     alongside it are discarded.
+//   - If the Source implements io.Closer, Close is called exactly once: when
+//     the Source signals that it is done, or when the Paginated is closed,
+//     whichever comes first. A stage should close its upstream Paginated from
+//     its own Close.
+type Source[T any] interface {
 	Fetch(atLeast int) ([]T, error)
 }
 
+// DeadlineSource is implemented by data sources that can stop early when a
+// deadline is near, so that Paginated.FetchWithin can return on time.
+//
+// Unlike Fetch, FetchWithin may return fewer than atLeast results without
+// being done, so long as it reports timedOut. A zero deadline means there is
+// no deadline.
+//
+// A Source that doesn't implement DeadlineSource still works with FetchWithin:
+// it just isn't called at all once the deadline has passed.
+type DeadlineSource[T any] interface {
+	Source[T]
+	FetchWithin(atLeast int, deadline time.Time) (results []T, timedOut bool, err error)
+}
+
 // Paginated is a struct for retrieving elements in batches from a data source.
 //
 // It's designed to replace channel pipelines in programs where operating element-by-
@@ -24,44 +62,85 @@ type fetch[T any] interface {
 // FetchRange(atLeast, atMost), which returns at least atLeast elements and at most
 // atMost elements. (inclusive)
 //
+// FetchBatch(atLeast) is like Fetch, but returns a FetchResult that says
+// outright whether the data source has run out. Exhausted() answers the same
+// question without fetching.
+//
 // Each method will produce less than `atLeast` elements once the data source runs out.
-// Further calls will produce nil.
+// Further calls will produce nil. (The exception is FetchWithin, which can also
+// produce less than `atLeast` elements when it runs out of time.)
 //
 // Any error will result in the immediate end of output. (In other words, you can't
 // recover any output generated before the error occurred.) Future calls to
 // Fetch or FetchMany will produce that error.
 //
+// Close() releases the data source early, along with everything upstream of it.
+// It happens automatically once the data source runs out or produces an error,
+// so it is only needed when abandoning a Paginated part of the way through.
+//
 // Paginated is thread-safe.
 type Paginated[T any] struct {
 	underlying   buffered[T]
 	mutex        *sync.Mutex
 	isExhausted  *bool // pointer so it isn't inadvertently copied
 	err          *error
+	closeErr     *error
 	atMostFactor float64
+	observer     observe.LateFetchObserver // nil unless the Source is one
 }
 
-// buffered augments Paginated with buffering behavior -- if a fetch implementor
+// buffered augments Paginated with buffering behavior -- if a Source
 // returns too many results, buffered will temporarily store all elements after
 // the first atMost elements.
+//
+// If the Paginated has a SpillPolicy, some of those elements may be on disk.
 type buffered[T any] struct {
-	underlying *fetch[T]
+	underlying *Source[T]
 	buffer     *[]T
+	spill      *spillState[T]
 }
 
-// wrap converts a fetch (the internal interface) to a Paginated (the external one)
-func wrap[T any](f fetch[T]) Paginated[T] {
+// FetchResult is the result of fetching a batch from a Paginated, with the
+// end of the stream stated explicitly rather than inferred from the length of
+// Items.
+type FetchResult[T any] struct {
+	// Items holds the elements fetched, if any.
+	Items []T
+
+	// Exhausted is true if the Paginated will produce no more elements after
+	// this batch, either because it ran out or because of an error.
+	Exhausted bool
+
+	// Err is the error that ended the Paginated, if there was one.
+	Err error
+}
+
+// FromSource converts a Source to a Paginated, adding buffering, locking and
+// error handling.
+//
+// See Source for the guarantees this provides.
+func FromSource[T any](s Source[T]) Paginated[T] {
+	return wrap(s)
+}
+
+// wrap converts a Source to a Paginated.
+func wrap[T any](f Source[T]) Paginated[T] {
 	isExhausted := false
-	var err error
+	var err, closeErr error
 	var buf []T
+	observer, _ := f.(observe.LateFetchObserver)
 	return Paginated[T]{
 		underlying: buffered[T]{
 			underlying: &f,
 			buffer:     &buf,
+			spill:      &spillState[T]{},
 		},
 		mutex:        &sync.Mutex{},
 		isExhausted:  &isExhausted,
 		err:          &err,
+		closeErr:     &closeErr,
 		atMostFactor: 2.0,
+		observer:     observer,
 	}
 
 }
@@ -71,6 +150,7 @@ func wrap[T any](f fetch[T]) Paginated[T] {
 // Not providing that error code will result in an empty Paginated.
 func signal[A any](err error) Paginated[A] {
 	exh := true
+	var closeErr error
 	return Paginated[A]{
 		underlying: buffered[A]{
 			underlying: nil,
@@ -79,11 +159,77 @@ func signal[A any](err error) Paginated[A] {
 		mutex:        &sync.Mutex{},
 		isExhausted:  &exh,
 		err:          &err,
+		closeErr:     &closeErr,
 		atMostFactor: 2.0,
 	}
 }
 
-// Fetch fetches at least `atLeast` elements from the underlying `Fetch` object.
+// Exhausted reports whether the Paginated is done: that is, whether it has run
+// out of elements or produced an error. Once a Paginated is exhausted, Fetch
+// will only ever produce nil (and the error, if any).
+//
+// A Paginated doesn't know it is exhausted until a fetch comes up short, so
+// Exhausted can be false even if no elements remain.
+func (p Paginated[T]) Exhausted() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return *p.isExhausted
+}
+
+// FetchBatch is like Fetch, but also says whether the Paginated is exhausted
+// after this batch.
+func (p Paginated[T]) FetchBatch(atLeast int) FetchResult[T] {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	result, _, err := p._fetchLocked(atLeast, int(atLeast*int(p.atMostFactor)), deadline{})
+	return FetchResult[T]{Items: result, Exhausted: *p.isExhausted, Err: err}
+}
+
+// Close ends the Paginated early, closing the underlying Source and everything
+// upstream of it. Later fetches produce nil, as if the Paginated had run out.
+//
+// A Paginated closes itself once it runs out or produces an error, so Close
+// is only needed when abandoning one part of the way through. Close is safe to
+// call more than once. It returns the error from closing the Source, including
+// one that happened when the Paginated closed itself.
+func (p Paginated[T]) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	*p.isExhausted = true
+	p.closeLocked()
+	return *p.closeErr
+}
+
+// closeLocked closes and releases the underlying Source if that hasn't
+// already happened.
+func (p Paginated[T]) closeLocked() {
+	if p.underlying.underlying == nil || *p.underlying.underlying == nil {
+		return
+	}
+
+	if closer, ok := (*p.underlying.underlying).(io.Closer); ok {
+		*p.closeErr = closer.Close()
+	}
+	*p.underlying.underlying = nil // allow this stuff to be freed
+	*p.underlying.buffer = nil
+	p.underlying.spill.cleanup()
+}
+
+// OnClose returns a Paginated with the same elements as p, which calls fn
+// after closing p.
+//
+// This is the place to release whatever a Func or SliceFunc reads from, such
+// as a database cursor or an HTTP response body. fn is called exactly once,
+// whether the Paginated runs out, produces an error, or is closed early.
+func (p Paginated[T]) OnClose(fn func() error) Paginated[T] {
+	out := wrap[T](&onClose[T]{underlying: p, fn: fn})
+	out.atMostFactor = p.atMostFactor
+	return out
+}
+
+// Fetch fetches at least `atLeast` elements from the underlying Source.
 // If there are not `atLeast` elements, it will reproduce whatever was found.
 //
 // Further calls (or errors) result in nil. Errors additionally result in an
@@ -91,16 +237,53 @@ func signal[A any](err error) Paginated[A] {
 //
 // Equivalent to FetchRange(atLeast, atLeast * 2).
 func (p Paginated[T]) Fetch(atLeast int) ([]T, error) {
-	return p._fetch(atLeast, int(atLeast*int(p.atMostFactor)))
+	result, _, err := p._fetch(atLeast, int(atLeast*int(p.atMostFactor)), deadline{})
+	return result, err
 }
 
-// FetchRange fetches at least `atLeast` elements from the underlying `Fetch`
-// object. If there are not `atLeast` elements, it will reproduce whatever was
+// FetchRange fetches at least `atLeast` elements from the underlying Source.
+// If there are not `atLeast` elements, it will reproduce whatever was
 // found.
 //
 // Further calls (or errors) result in nil. Errors additionally result in an
 // error value.
 func (p Paginated[T]) FetchRange(atLeast, atMost int) ([]T, error) {
+	result, _, err := p.fetchRangeWithin(atLeast, atMost, deadline{})
+	return result, err
+}
+
+// FetchWithin is like Fetch, but tries to return by deadline, even if that
+// means producing fewer than `atLeast` elements.
+//
+// If the result is short because time ran out, timedOut is true and the data
+// source is not exhausted: later calls can keep fetching. Otherwise, a short
+// result means the data source is exhausted, just like Fetch.
+//
+// FetchWithin can't interrupt a data source that is in the middle of producing
+// an element, so it may still return late. MapWindowed, Flatten, Concat and
+// Func check the deadline between elements (or batches) and stop pulling more
+// input once it is near. Map passes the deadline on, but maps whatever it gets
+// back without checking. Other stages only check it before they start.
+func (p Paginated[T]) FetchWithin(atLeast int, deadline time.Time) (results []T, timedOut bool, err error) {
+	return p.FetchWithinClock(atLeast, deadline, systemClock{})
+}
+
+// FetchWithinClock is FetchWithin, with the deadline measured by clock instead
+// of the system clock. The built-in stages pass clock upstream along with the
+// deadline, but a DeadlineSource is only given the deadline, so it needs to be
+// told about clock some other way. This is mostly useful in tests.
+func (p Paginated[T]) FetchWithinClock(atLeast int, at time.Time, clock Clock) (results []T, timedOut bool, err error) {
+	return p.fetchWithin(atLeast, deadline{at: at, clock: clock})
+}
+
+// fetchWithin is FetchWithin, for stages that want to pass their deadline on.
+func (p Paginated[T]) fetchWithin(atLeast int, d deadline) ([]T, bool, error) {
+	return p._fetch(atLeast, int(atLeast*int(p.atMostFactor)), d)
+}
+
+// fetchRangeWithin is FetchRange with a deadline, for stages that want to pass
+// their deadline on.
+func (p Paginated[T]) fetchRangeWithin(atLeast, atMost int, d deadline) ([]T, bool, error) {
 	// if it overrides the atMostFactor, respect that override
 	atMost2 := int(float64(atLeast) * p.atMostFactor)
 	if atMost > atMost2 {
@@ -109,26 +292,32 @@ func (p Paginated[T]) FetchRange(atLeast, atMost int) ([]T, error) {
 	if atMost < atLeast {
 		atMost = atLeast
 	}
-	return p._fetch(atLeast, atMost)
+	return p._fetch(atLeast, atMost, d)
 }
 
-func (p Paginated[T]) _fetch(atLeast, atMost int) ([]T, error) {
+func (p Paginated[T]) _fetch(atLeast, atMost int, d deadline) ([]T, bool, error) {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
+	return p._fetchLocked(atLeast, atMost, d)
+}
 
+func (p Paginated[T]) _fetchLocked(atLeast, atMost int, d deadline) ([]T, bool, error) {
 	if *p.isExhausted {
-		return nil, *p.err
+		if p.observer != nil {
+			p.observer.LateFetch(atLeast)
+		}
+		return nil, false, *p.err
 	}
 	if atLeast == 0 {
-		return nil, nil
+		return nil, false, nil
 	}
 
-	result, err := p.underlying._fetch(atLeast, atMost)
-	if len(result) < atLeast || err != nil {
+	result, timedOut, err := p.underlying._fetch(atLeast, atMost, d)
+	timedOut = timedOut && len(result) < atLeast && err == nil
+	if (len(result) < atLeast && !timedOut) || err != nil {
 		*p.isExhausted = true
-		*p.underlying.underlying = nil // allow this stuff to be freed
-		*p.underlying.buffer = nil
 		*p.err = err
+		p.closeLocked()
 	}
 
 	if err != nil {
@@ -136,37 +325,123 @@ func (p Paginated[T]) _fetch(atLeast, atMost int) ([]T, error) {
 		result = nil
 	}
 
-	return result, err
+	return result, timedOut, err
 }
 
-func (b buffered[T]) _fetch(atLeast int, atMost int) ([]T, error) {
-	if len(*b.buffer) > atMost {
-		chunk := (*b.buffer)[:atMost]
-		*b.buffer = (*b.buffer)[atMost:]
-		return chunk, nil
+func (b buffered[T]) _fetch(atLeast int, atMost int, d deadline) ([]T, bool, error) {
+	if b.length() >= atLeast {
+		chunk, err := b.take(atMost)
+		return chunk, false, err
 	}
 
-	if len(*b.buffer) >= atLeast {
-		chunk := *b.buffer
-		*b.buffer = nil
-		return chunk, nil
+	nWanted := atLeast - b.length()
+	buf, timedOut, err := fetchUnderlying(*b.underlying, nWanted, d)
+	if err != nil {
+		return nil, false, err
 	}
 
-	nWanted := atLeast - len(*b.buffer)
-	buf, err := (*b.underlying).Fetch(nWanted)
+	// hand out at most atMost, and only buffer what's left over. What's
+	// already buffered is less than atLeast, so it all goes out first
+	chunk, err := b.take(atMost)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	n := atMost - len(chunk)
+	if n > len(buf) {
+		n = len(buf)
+	}
+	if n > 0 && len(chunk) == 0 {
+		chunk = buf[:n:n]
+	} else if n > 0 {
+		chunk = append(chunk, buf[:n]...)
+	}
+	if err := b.push(buf[n:]); err != nil {
+		return nil, false, err
+	}
+	return chunk, timedOut, nil
+}
+
+// deadline is a FetchWithin deadline, along with the Clock it is measured by.
+// The zero deadline means there is no deadline.
+type deadline struct {
+	at    time.Time
+	clock Clock
+}
+
+// now is the time according to the deadline's clock.
+func (d deadline) now() time.Time {
+	if d.clock == nil {
+		return time.Now()
+	}
+	return d.clock.Now()
+}
+
+// near reports whether there is a deadline, and it would be missed by
+// starting something expected to take `estimate`.
+func (d deadline) near(estimate time.Duration) bool {
+	return !d.at.IsZero() && !d.now().Add(estimate).Before(d.at)
+}
+
+// deadlineSource is implemented by the built-in stages, which take the whole
+// deadline so they can pass its clock upstream.
+type deadlineSource[T any] interface {
+	fetchWithin(atLeast int, d deadline) ([]T, bool, error)
+}
+
+// fetchUnderlying fetches from a data source, passing the deadline on if the
+// data source supports one.
+func fetchUnderlying[T any](f Source[T], atLeast int, d deadline) ([]T, bool, error) {
+	if d.at.IsZero() {
+		out, err := f.Fetch(atLeast)
+		return out, false, err
 	}
-	*b.buffer = append(*b.buffer, buf...)
 
-	// now repeat the original logic for dealing with at most
-	if len(*b.buffer) > atMost {
-		chunk := (*b.buffer)[:atMost]
-		*b.buffer = (*b.buffer)[atMost:]
-		return chunk, nil
+	if fw, ok := f.(deadlineSource[T]); ok {
+		return fw.fetchWithin(atLeast, d)
+	}
+	if fw, ok := f.(DeadlineSource[T]); ok {
+		return fw.FetchWithin(atLeast, d.at)
+	}
+
+	// the best we can do is not start
+	if d.near(0) {
+		return nil, true, nil
+	}
+	out, err := f.Fetch(atLeast)
+	return out, false, err
+}
+
+type onClose[T any] struct {
+	underlying Paginated[T]
+	fn         func() error
+}
+
+func (o *onClose[T]) Fetch(atLeast int) ([]T, error) {
+	return o.underlying.Fetch(atLeast)
+}
+
+func (o *onClose[T]) fetchWithin(atLeast int, d deadline) ([]T, bool, error) {
+	return o.underlying.fetchWithin(atLeast, d)
+}
+
+func (o *onClose[T]) Close() error {
+	return closeAll(o.underlying.Close(), o.fn())
+}
+
+// closeAll returns the first of several errors from closing things.
+func closeAll(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+func (o *onClose[T]) Checkpoint() ([]byte, error) {
+	return o.underlying.Checkpoint()
+}
 
-	chunk := *b.buffer
-	*b.buffer = nil
-	return chunk, nil
+func (o *onClose[T]) Restore(checkpoint []byte) error {
+	return o.underlying.restore(checkpoint)
 }
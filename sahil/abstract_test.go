@@ -3,6 +3,7 @@ package sahil
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -31,7 +32,7 @@ func TestNoCallsOnceExhausted(t *testing.T) {
 	assert.Nil(t, err)
 	assert.EqualValues(t, []int{1, 2, 3, 4, 5}, results)
 	assert.True(t, called)
-	assert.True(t, *src.isExhausted)
+	assert.True(t, src.Exhausted())
 
 	called = false
 	results, err = src.Fetch(5)
@@ -54,17 +55,17 @@ func TestFetchAtLeast(t *testing.T) {
 	results, err := src.Fetch(2)
 	assert.Nil(t, err)
 	assert.EqualValues(t, []int{1, 2}, results)
-	assert.False(t, *src.isExhausted)
+	assert.False(t, src.Exhausted())
 
 	results, err = src.Fetch(2)
 	assert.Nil(t, err)
 	assert.EqualValues(t, []int{3, 4}, results)
-	assert.False(t, *src.isExhausted)
+	assert.False(t, src.Exhausted())
 
 	results, err = src.Fetch(3)
 	assert.Nil(t, err)
 	assert.EqualValues(t, results, []int{5})
-	assert.True(t, *src.isExhausted)
+	assert.True(t, src.Exhausted())
 }
 
 type bigResultsTest struct{}
@@ -79,17 +80,17 @@ func TestBufferBigResults(t *testing.T) {
 	results, err := src.Fetch(2)
 	assert.Nil(t, err)
 	assert.EqualValues(t, []int{1, 2, 3, 4}, results)
-	assert.False(t, *src.isExhausted)
+	assert.False(t, src.Exhausted())
 
 	results, err = src.Fetch(2)
 	assert.Nil(t, err)
 	assert.EqualValues(t, []int{5, 6, 7, 8}, results)
-	assert.False(t, *src.isExhausted)
+	assert.False(t, src.Exhausted())
 
 	results, err = src.Fetch(3)
 	assert.Nil(t, err)
 	assert.EqualValues(t, []int{9, 10, 1, 2, 3, 4}, results)
-	assert.False(t, *src.isExhausted)
+	assert.False(t, src.Exhausted())
 }
 
 func TestSignalEmpty(t *testing.T) {
@@ -105,3 +106,248 @@ func TestSignalErr(t *testing.T) {
 	assert.Equal(t, len(results), 0)
 	assert.EqualError(t, err, "barf")
 }
+
+// slowCounter counts up from 1, moving clock forward by delay for each
+// element.
+func slowCounter(clock *fakeClock, delay time.Duration) Paginated[int] {
+	i := 0
+	return Func(func() (int, error) {
+		clock.now = clock.now.Add(delay)
+		i += 1
+		return i, nil
+	})
+}
+
+func TestFetchWithinTimeout(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	src := slowCounter(clock, 10*time.Millisecond)
+
+	results, timedOut, err := src.FetchWithinClock(1000, clock.now.Add(55*time.Millisecond), clock)
+	assert.Nil(t, err)
+	assert.True(t, timedOut)
+	assert.EqualValues(t, []int{1, 2, 3, 4, 5, 6}, results)
+	assert.False(t, src.Exhausted())
+
+	// a timeout isn't exhaustion, so we can keep going where we left off
+	more, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{7, 8}, more)
+}
+
+func TestFetchWithinExhausted(t *testing.T) {
+	src := Slice([]int{1, 2, 3})
+
+	results, timedOut, err := src.FetchWithin(5, time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+	assert.False(t, timedOut)
+	assert.EqualValues(t, []int{1, 2, 3}, results)
+	assert.True(t, src.Exhausted())
+}
+
+func TestFetchWithinMapWindowed(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	src := MapWindowed(slowCounter(clock, 10*time.Millisecond), func(xs []int) ([]int, error) {
+		return xs, nil
+	})
+
+	start := clock.now
+	results, timedOut, err := src.FetchWithinClock(1000, start.Add(55*time.Millisecond), clock)
+	assert.Nil(t, err)
+	assert.True(t, timedOut)
+	assert.EqualValues(t, []int{1, 2, 3, 4, 5, 6}, results)
+	assert.Equal(t, 60*time.Millisecond, clock.now.Sub(start))
+}
+
+func TestFetchWithinMap(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	src := Map(slowCounter(clock, 10*time.Millisecond), func(x int) (int, error) {
+		return -x, nil
+	})
+
+	results, timedOut, err := src.FetchWithinClock(1000, clock.now.Add(25*time.Millisecond), clock)
+	assert.Nil(t, err)
+	assert.True(t, timedOut)
+	assert.EqualValues(t, []int{-1, -2, -3}, results)
+}
+
+func TestFetchWithinFlatten(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	src := SliceFunc(func() ([]int, error) {
+		clock.now = clock.now.Add(10 * time.Millisecond)
+		return []int{1, 2}, nil
+	})
+
+	start := clock.now
+	results, timedOut, err := src.FetchWithinClock(1000, start.Add(55*time.Millisecond), clock)
+	assert.Nil(t, err)
+	assert.True(t, timedOut)
+	assert.Len(t, results, 10)
+	assert.Equal(t, 60*time.Millisecond, clock.now.Sub(start))
+}
+
+func TestFetchWithinUnsupported(t *testing.T) {
+	// Chunk doesn't know about deadlines, but won't start once one has passed
+	src := Chunk(Slice([]int{1, 2, 3}), 2)
+
+	results, timedOut, err := src.FetchWithin(1, time.Now().Add(-time.Second))
+	assert.Nil(t, err)
+	assert.True(t, timedOut)
+	assert.Equal(t, 0, len(results))
+
+	results, timedOut, err = src.FetchWithin(1, time.Now().Add(time.Hour))
+	assert.Nil(t, err)
+	assert.False(t, timedOut)
+	assert.EqualValues(t, [][]int{{1, 2}}, results)
+}
+
+func TestFetchBatch(t *testing.T) {
+	src := Slice([]int{1, 2, 3, 4})
+
+	result := src.FetchBatch(2)
+	assert.EqualValues(t, FetchResult[int]{Items: []int{1, 2}}, result)
+	assert.False(t, src.Exhausted())
+
+	// an exact fit doesn't look exhausted yet
+	result = src.FetchBatch(2)
+	assert.EqualValues(t, FetchResult[int]{Items: []int{3, 4}}, result)
+	assert.False(t, src.Exhausted())
+
+	result = src.FetchBatch(2)
+	assert.EqualValues(t, FetchResult[int]{Exhausted: true}, result)
+	assert.True(t, src.Exhausted())
+}
+
+func TestFetchBatchErr(t *testing.T) {
+	src := signal[int](errors.New("barf"))
+	assert.True(t, src.Exhausted())
+
+	result := src.FetchBatch(1)
+	assert.Equal(t, 0, len(result.Items))
+	assert.True(t, result.Exhausted)
+	assert.EqualError(t, result.Err, "barf")
+}
+
+// countdownSource is a custom Source producing n, n-1, ..., 1 in batches of
+// three, and counting how many times it is called.
+type countdownSource struct {
+	n     int
+	calls int
+}
+
+func (c *countdownSource) Fetch(atLeast int) ([]int, error) {
+	c.calls += 1
+	var out []int
+	for len(out) < atLeast && c.n > 0 {
+		for i := 0; i < 3 && c.n > 0; i++ {
+			out = append(out, c.n)
+			c.n -= 1
+		}
+	}
+	return out, nil
+}
+
+func TestFromSource(t *testing.T) {
+	source := &countdownSource{n: 5}
+	src := FromSource[int](source)
+
+	results, err := src.Fetch(1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{5, 4}, results)
+
+	// the rest of the first batch was buffered
+	results, err = src.Fetch(1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{3}, results)
+	assert.Equal(t, 1, source.calls)
+
+	results, err = src.Fetch(3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{2, 1}, results)
+	assert.True(t, src.Exhausted())
+
+	// the source isn't called again once it's done
+	results, err = src.Fetch(3)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+	assert.Equal(t, 2, source.calls)
+}
+
+func closeCounter(n *int, err error) func() error {
+	return func() error {
+		*n += 1
+		return err
+	}
+}
+
+func TestClose(t *testing.T) {
+	closed := 0
+	i := 0
+	cursor := SliceFunc(func() ([]int, error) {
+		i += 1
+		return []int{i, i, i}, nil
+	}).OnClose(closeCounter(&closed, nil))
+
+	src := Filter(Map(cursor, func(x int) (int, error) {
+		return x * 2, nil
+	}), func(x int) (bool, error) {
+		return x%4 == 0, nil
+	})
+
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, results)
+	assert.Equal(t, 0, closed)
+
+	// closing at the end of the pipeline reaches all the way back
+	assert.Nil(t, src.Close())
+	assert.Equal(t, 1, closed)
+	assert.True(t, cursor.Exhausted())
+
+	results, err = src.Fetch(2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+
+	// closing again is harmless
+	assert.Nil(t, src.Close())
+	assert.Equal(t, 1, closed)
+}
+
+func TestCloseOnExhaustion(t *testing.T) {
+	closed := 0
+	src := Map(Slice([]int{1, 2, 3}).OnClose(closeCounter(&closed, nil)), func(x int) (int, error) {
+		return x, nil
+	})
+
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 2}, results)
+	assert.Equal(t, 0, closed)
+
+	results, err = src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{3}, results)
+	assert.Equal(t, 1, closed)
+
+	assert.Nil(t, src.Close())
+	assert.Equal(t, 1, closed)
+}
+
+func TestCloseOnError(t *testing.T) {
+	closed := 0
+	src := Func(func() (int, error) {
+		return 0, errors.New("CURSOR ERROR")
+	}).OnClose(closeCounter(&closed, errors.New("CLOSE ERROR")))
+
+	results, err := src.Fetch(1)
+	assert.Equal(t, 0, len(results))
+	assert.EqualError(t, err, "CURSOR ERROR")
+	assert.Equal(t, 1, closed)
+
+	// the error from closing automatically is kept for Close to report
+	assert.EqualError(t, src.Close(), "CLOSE ERROR")
+	assert.Equal(t, 1, closed)
+}
+
+func TestCloseEmpty(t *testing.T) {
+	assert.Nil(t, Empty[int]().Close())
+}
@@ -0,0 +1,134 @@
+package sahil
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+)
+
+// ErrNotCheckpointable is produced when checkpointing a pipeline that has a
+// stage (or data source) in it that can't save its position.
+var ErrNotCheckpointable = errors.New("stage does not support checkpoints")
+
+// Checkpointer is implemented by Sources that can save their position and
+// later pick up from it.
+//
+// Checkpoint is only called between fetches, and Restore is only called on a
+// freshly constructed Source that hasn't been fetched from yet. A stage
+// should include its upstream Paginated's checkpoint in its own.
+//
+// Sources that don't implement Checkpointer make Paginated.Checkpoint fail
+// with ErrNotCheckpointable.
+type Checkpointer interface {
+	Checkpoint() ([]byte, error)
+	Restore(checkpoint []byte) error
+}
+
+type paginatedCheckpoint[T any] struct {
+	Exhausted bool
+	Buffer    []T
+	Source    []byte
+}
+
+// Checkpoint saves the position of a Paginated, so that a later process can
+// pick up where this one left off using Resume.
+//
+// The checkpoint covers the whole pipeline: every stage and the data source
+// at the start of it, along with any elements that have been fetched but not
+// handed out yet. That means every stage must support checkpoints. Slice,
+// Cursor, Map, Filter, MapWindowed, MapWithState, Scan, Chunk, Window, Concat
+// and RateLimit do. Func, SliceFunc, Channel, Flatten and FlatMap don't, since
+// they can't describe where they are.
+//
+// Buffered elements and stage state are saved with encoding/gob, so they must
+// be types gob can encode. Buffered elements that have spilled to disk are
+// brought back into memory to be saved.
+//
+// A Paginated that has produced an error can't be checkpointed: Checkpoint
+// returns that error.
+func (p Paginated[T]) Checkpoint() ([]byte, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if *p.err != nil {
+		return nil, *p.err
+	}
+	if *p.isExhausted {
+		return encodeCheckpoint(paginatedCheckpoint[T]{Exhausted: true})
+	}
+
+	checkpointer, ok := (*p.underlying.underlying).(Checkpointer)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrNotCheckpointable, *p.underlying.underlying)
+	}
+	source, err := checkpointer.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	// anything spilled to disk has to be saved too
+	if err := p.underlying.unspill(); err != nil {
+		return nil, err
+	}
+
+	return encodeCheckpoint(paginatedCheckpoint[T]{
+		Buffer: *p.underlying.buffer,
+		Source: source,
+	})
+}
+
+// Resume restores a Paginated to a position saved by Checkpoint.
+//
+// p must be a newly built pipeline of the same shape as the one that was
+// checkpointed, over the same data, and must not have been fetched from yet.
+// For instance, a pipeline built over Slice(rows) can only be resumed if rows
+// is the same slice as last time. On success, Resume returns p, ready to
+// continue where the checkpoint left off.
+func Resume[T any](p Paginated[T], checkpoint []byte) (Paginated[T], error) {
+	if err := p.restore(checkpoint); err != nil {
+		return Paginated[T]{}, err
+	}
+	return p, nil
+}
+
+func (p Paginated[T]) restore(checkpoint []byte) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var saved paginatedCheckpoint[T]
+	if err := decodeCheckpoint(checkpoint, &saved); err != nil {
+		return err
+	}
+
+	if saved.Exhausted {
+		*p.isExhausted = true
+		p.closeLocked()
+		return nil
+	}
+	if *p.isExhausted {
+		return errors.New("can't resume a Paginated that has already run out")
+	}
+
+	checkpointer, ok := (*p.underlying.underlying).(Checkpointer)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrNotCheckpointable, *p.underlying.underlying)
+	}
+	if err := checkpointer.Restore(saved.Source); err != nil {
+		return err
+	}
+	*p.underlying.buffer = saved.Buffer
+	return nil
+}
+
+func encodeCheckpoint(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeCheckpoint(checkpoint []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(checkpoint)).Decode(v)
+}
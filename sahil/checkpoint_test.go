@@ -0,0 +1,146 @@
+package sahil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func checkpointPipeline(input []int) Paginated[int] {
+	doubled := Map(Slice(input), func(x int) (int, error) {
+		return x * 2, nil
+	})
+	return Filter(doubled, func(x int) (bool, error) {
+		return x%3 != 0, nil
+	})
+}
+
+func TestCheckpointResume(t *testing.T) {
+	var input []int
+	for i := 0; i < 30; i++ {
+		input = append(input, i)
+	}
+
+	src := checkpointPipeline(input)
+	before, err := src.Fetch(5)
+	assert.Nil(t, err)
+
+	checkpoint, err := src.Checkpoint()
+	assert.Nil(t, err)
+
+	// the uninterrupted pipeline and the resumed one agree on what's next
+	expected, err := src.Fetch(100)
+	assert.Nil(t, err)
+
+	resumed, err := Resume(checkpointPipeline(input), checkpoint)
+	assert.Nil(t, err)
+	after, err := resumed.Fetch(100)
+	assert.Nil(t, err)
+	assert.EqualValues(t, expected, after)
+
+	all, err := checkpointPipeline(input).Fetch(100)
+	assert.Nil(t, err)
+	assert.EqualValues(t, all, append(before, after...))
+}
+
+func pagesOf5(start int) ([]int, int, error) {
+	if start >= 20 {
+		return nil, start, EOF
+	}
+	return []int{start, start + 1, start + 2, start + 3, start + 4}, start + 5, nil
+}
+
+func TestCheckpointBuffer(t *testing.T) {
+	src := Cursor(0, pagesOf5)
+
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{0, 1, 2, 3}, results)
+
+	// element 4 was fetched from the cursor but is still in the buffer
+	checkpoint, err := src.Checkpoint()
+	assert.Nil(t, err)
+
+	resumed, err := Resume(Cursor(0, pagesOf5), checkpoint)
+	assert.Nil(t, err)
+	results, err = resumed.Fetch(3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{4, 5, 6, 7, 8, 9}, results)
+}
+
+func TestCheckpointScan(t *testing.T) {
+	build := func() Paginated[int] {
+		return Scan(Slice([]int{1, 2, 3, 4, 5}), 0, func(total, x int) (int, error) {
+			return total + x, nil
+		})
+	}
+
+	src := build()
+	_, err := src.Fetch(2)
+	assert.Nil(t, err)
+	checkpoint, err := src.Checkpoint()
+	assert.Nil(t, err)
+
+	resumed, err := Resume(build(), checkpoint)
+	assert.Nil(t, err)
+	results, err := resumed.Fetch(3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{6, 10, 15}, results)
+}
+
+func TestCheckpointConcat(t *testing.T) {
+	closed := 0
+	build := func() Paginated[int] {
+		return Concat(
+			Slice([]int{1, 2}).OnClose(closeCounter(&closed, nil)),
+			Slice([]int{3, 4}),
+			Slice([]int{5, 6}),
+		)
+	}
+
+	src := build()
+	_, err := src.Fetch(3)
+	assert.Nil(t, err)
+	checkpoint, err := src.Checkpoint()
+	assert.Nil(t, err)
+
+	closed = 0
+	resumed, err := Resume(build(), checkpoint)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, closed)
+
+	results, err := resumed.Fetch(5)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{4, 5, 6}, results)
+}
+
+func TestCheckpointExhausted(t *testing.T) {
+	src := Slice([]int{1, 2})
+	_, err := src.Fetch(3)
+	assert.Nil(t, err)
+
+	checkpoint, err := src.Checkpoint()
+	assert.Nil(t, err)
+
+	resumed, err := Resume(Slice([]int{1, 2}), checkpoint)
+	assert.Nil(t, err)
+	assert.True(t, resumed.Exhausted())
+}
+
+func TestCheckpointUnsupported(t *testing.T) {
+	src := Map(Func(func() (int, error) { return 1, nil }), func(x int) (int, error) {
+		return x, nil
+	})
+	_, err := src.Checkpoint()
+	assert.True(t, errors.Is(err, ErrNotCheckpointable))
+
+	_, err = Distinct(Slice([]int{1, 1, 2})).Checkpoint()
+	assert.True(t, errors.Is(err, ErrNotCheckpointable))
+}
+
+func TestCheckpointErr(t *testing.T) {
+	src := signal[int](errors.New("barf"))
+	_, err := src.Checkpoint()
+	assert.EqualError(t, err, "barf")
+}
@@ -0,0 +1,76 @@
+package sahil
+
+type chunk[T any] struct {
+	underlying Paginated[T]
+	size       int
+	rest       []T
+}
+
+// Chunk splits a Paginated into slices of exactly `size` elements.
+//
+// Every chunk is full except possibly the last one, which holds whatever is
+// left when the underlying Paginated runs out.
+//
+// Chunk fetches from the underlying Paginated in batches large enough for all
+// of the chunks it was asked for, rather than once per chunk.
+func Chunk[T any](p Paginated[T], size int) Paginated[[]T] {
+	if size < 1 {
+		size = 1
+	}
+	return wrap[[]T](&chunk[T]{
+		underlying: p,
+		size:       size,
+	})
+}
+
+func (c *chunk[T]) Fetch(atLeast int) ([][]T, error) {
+	nWanted := atLeast*c.size - len(c.rest)
+	if nWanted > 0 && !c.underlying.Exhausted() {
+		input, err := c.underlying.Fetch(nWanted)
+		if err != nil {
+			return nil, err
+		}
+		c.rest = append(c.rest, input...)
+	}
+
+	var out [][]T
+	for len(c.rest) >= c.size {
+		// copy so that callers can't see each other's chunks through append
+		next := make([]T, c.size)
+		copy(next, c.rest)
+		out = append(out, next)
+		c.rest = c.rest[c.size:]
+	}
+
+	if c.underlying.Exhausted() && len(c.rest) > 0 {
+		out = append(out, c.rest)
+		c.rest = nil
+	}
+	return out, nil
+}
+
+func (c *chunk[T]) Close() error {
+	return c.underlying.Close()
+}
+
+type chunkCheckpoint[T any] struct {
+	Rest       []T
+	Underlying []byte
+}
+
+func (c *chunk[T]) Checkpoint() ([]byte, error) {
+	underlying, err := c.underlying.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	return encodeCheckpoint(chunkCheckpoint[T]{Rest: c.rest, Underlying: underlying})
+}
+
+func (c *chunk[T]) Restore(checkpoint []byte) error {
+	var saved chunkCheckpoint[T]
+	if err := decodeCheckpoint(checkpoint, &saved); err != nil {
+		return err
+	}
+	c.rest = saved.Rest
+	return c.underlying.restore(saved.Underlying)
+}
@@ -0,0 +1,60 @@
+package sahil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunk(t *testing.T) {
+	src := Chunk(Slice([]int{1, 2, 3, 4, 5, 6, 7, 8}), 3)
+
+	results, err := src.Fetch(1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, [][]int{{1, 2, 3}}, results)
+
+	results, err = src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, [][]int{{4, 5, 6}, {7, 8}}, results)
+
+	results, err = src.Fetch(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+}
+
+func TestChunkBatches(t *testing.T) {
+	// asking for several chunks should only hit the source once
+	calls := 0
+	src := Chunk(SliceFunc(func() ([]int, error) {
+		calls += 1
+		if calls > 10 {
+			return nil, EOF
+		}
+		return []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, nil
+	}), 2)
+
+	results, err := src.Fetch(5)
+	assert.Nil(t, err)
+	assert.Len(t, results, 5)
+	assert.Equal(t, 1, calls)
+}
+
+func TestChunkErr(t *testing.T) {
+	i := 0
+	src := Chunk(Func(func() (int, error) {
+		i += 1
+		if i > 4 {
+			return 0, errors.New("CHUNK ERROR")
+		}
+		return i, nil
+	}), 2)
+
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, [][]int{{1, 2}, {3, 4}}, results)
+
+	results, err = src.Fetch(1)
+	assert.Equal(t, 0, len(results))
+	assert.EqualError(t, err, "CHUNK ERROR")
+}
@@ -0,0 +1,32 @@
+// Package codec reads and writes Paginated streams in common file formats.
+//
+// Sources read only as much of their input as each Fetch needs. Sinks fetch
+// from a Paginated in batches and write each batch out as they go.
+package codec
+
+import (
+	"fmt"
+	"io"
+)
+
+// LineError is an error with the line of the input it happened on.
+type LineError struct {
+	Line int // 1-based
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// closeReader closes r if it can be closed.
+func closeReader(r io.Reader) error {
+	if closer, ok := r.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
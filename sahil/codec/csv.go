@@ -0,0 +1,400 @@
+package codec
+
+import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/Nyeogmi/sahil-go/sahil"
+)
+
+// CSVOptions configures CSV reading and writing. The zero value reads and
+// writes standard comma-separated files with a header row.
+type CSVOptions struct {
+	// Comma is the field delimiter. The default is ','.
+	Comma rune
+
+	// Comment, if set, marks lines to skip when reading.
+	Comment rune
+
+	// Header gives the column names for input without a header row. When it
+	// is set, the first row of the input is data. When writing, it is
+	// ignored.
+	Header []string
+
+	// NoHeader makes WriteCSV leave out the header row.
+	NoHeader bool
+
+	// Strict makes CSV fail if the input has a column that no struct field
+	// maps to. Otherwise, such columns are ignored.
+	Strict bool
+}
+
+// CSVError is an error converting a CSV field to or from a struct field.
+type CSVError struct {
+	Row    int    // 1-based record number, counting the header row
+	Line   int    // 1-based line of the input the field starts on, when reading
+	Column int    // 1-based field number within the record
+	Field  string // column name
+	Err    error
+}
+
+func (e *CSVError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("row %d (line %d), column %d (%q): %v", e.Row, e.Line, e.Column, e.Field, e.Err)
+	}
+	return fmt.Sprintf("row %d, column %d (%q): %v", e.Row, e.Column, e.Field, e.Err)
+}
+
+func (e *CSVError) Unwrap() error {
+	return e.Err
+}
+
+type csvRecords struct {
+	source io.Reader
+	reader *csv.Reader
+}
+
+// CSVRecords reads a CSV file as raw records, producing one []string per row.
+// The header row, if there is one, is the first record.
+//
+// Malformed input ends the Paginated with a *csv.ParseError, which gives the
+// line and column of the problem.
+//
+// If r is an io.Closer, it is closed when the Paginated is closed, which
+// happens automatically once the input runs out or produces an error.
+func CSVRecords(r io.Reader, opts CSVOptions) sahil.Paginated[[]string] {
+	return sahil.FromSource[[]string](&csvRecords{
+		source: r,
+		reader: newCSVReader(r, opts),
+	})
+}
+
+func (c *csvRecords) Fetch(atLeast int) ([][]string, error) {
+	var out [][]string
+	for len(out) < atLeast {
+		record, err := c.reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		out = append(out, record)
+	}
+	return out, nil
+}
+
+func (c *csvRecords) Close() error {
+	return closeReader(c.source)
+}
+
+type csvStructs[T any] struct {
+	source  io.Reader
+	reader  *csv.Reader
+	opts    CSVOptions
+	header  []string
+	columns []*csvField // for each column of the input, the field it maps to
+	row     int
+}
+
+// CSV reads a CSV file into structs, producing one T per row.
+//
+// Columns are matched to the fields of T by name: a field tagged `csv:"name"`
+// reads the column called name, and an untagged exported field reads the
+// column with the same name as the field. Fields tagged `csv:"-"` are left
+// alone. The column names come from the first row of the input, unless
+// opts.Header provides them.
+//
+// Fields can be strings, bools, integers, floats, anything implementing
+// encoding.TextUnmarshaler, or pointers to any of those. An empty value in a
+// pointer field leaves it nil.
+//
+// A field that fails to convert ends the Paginated with a *CSVError giving
+// its position. Malformed input ends it with a *csv.ParseError.
+//
+// If r is an io.Closer, it is closed when the Paginated is closed, which
+// happens automatically once the input runs out or produces an error.
+func CSV[T any](r io.Reader, opts CSVOptions) sahil.Paginated[T] {
+	return sahil.FromSource[T](&csvStructs[T]{
+		source: r,
+		reader: newCSVReader(r, opts),
+		opts:   opts,
+	})
+}
+
+func (c *csvStructs[T]) Fetch(atLeast int) ([]T, error) {
+	if c.columns == nil {
+		if err := c.readHeader(); err != nil {
+			return nil, err
+		}
+	}
+
+	var out []T
+	for len(out) < atLeast {
+		record, err := c.reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		c.row += 1
+
+		var t T
+		v := reflect.ValueOf(&t).Elem()
+		for i, value := range record {
+			if i >= len(c.columns) || c.columns[i] == nil {
+				continue
+			}
+			if err := c.columns[i].parse(v, value); err != nil {
+				line, _ := c.reader.FieldPos(i)
+				return nil, &CSVError{Row: c.row, Line: line, Column: i + 1, Field: c.header[i], Err: err}
+			}
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+func (c *csvStructs[T]) readHeader() error {
+	fields, err := csvFields(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return err
+	}
+
+	c.header = c.opts.Header
+	if c.header == nil {
+		c.header, err = c.reader.Read()
+		if err == io.EOF {
+			c.header = []string{}
+		} else if err != nil {
+			return err
+		}
+		c.row += 1
+	}
+
+	byName := map[string]*csvField{}
+	for i := range fields {
+		byName[fields[i].name] = &fields[i]
+	}
+
+	c.columns = make([]*csvField, len(c.header))
+	for i, name := range c.header {
+		field, ok := byName[name]
+		if !ok && c.opts.Strict {
+			return &CSVError{Row: c.row, Column: i + 1, Field: name, Err: errors.New("no field for column")}
+		}
+		c.columns[i] = field
+	}
+	return nil
+}
+
+func (c *csvStructs[T]) Close() error {
+	return closeReader(c.source)
+}
+
+// WriteCSVRecords writes raw records from p to w as CSV, fetching batchSize
+// records at a time.
+//
+// If writing fails, p is closed and the error is returned.
+func WriteCSVRecords(w io.Writer, p sahil.Paginated[[]string], batchSize int, opts CSVOptions) error {
+	writer := newCSVWriter(w, opts)
+	return drain(p, batchSize, func(batch [][]string) error {
+		return writer.WriteAll(batch)
+	})
+}
+
+// WriteCSV writes structs from p to w as CSV, fetching batchSize structs at a
+// time. Fields map to columns the same way as they do for CSV, and the header
+// row is written first unless opts.NoHeader is set.
+//
+// A field that fails to convert produces a *CSVError. If writing fails, p is
+// closed and the error is returned.
+func WriteCSV[T any](w io.Writer, p sahil.Paginated[T], batchSize int, opts CSVOptions) error {
+	fields, err := csvFields(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		p.Close()
+		return err
+	}
+
+	writer := newCSVWriter(w, opts)
+	row := 0
+	if !opts.NoHeader {
+		header := make([]string, len(fields))
+		for i, field := range fields {
+			header[i] = field.name
+		}
+		if err := writer.Write(header); err != nil {
+			p.Close()
+			return err
+		}
+		row += 1
+	}
+
+	err = drain(p, batchSize, func(batch []T) error {
+		for i := range batch {
+			row += 1
+			v := reflect.ValueOf(&batch[i]).Elem()
+
+			record := make([]string, len(fields))
+			for j, field := range fields {
+				value, err := field.format(v)
+				if err != nil {
+					return &CSVError{Row: row, Column: j + 1, Field: field.name, Err: err}
+				}
+				record[j] = value
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	// the header still needs writing out if p was empty
+	writer.Flush()
+	return writer.Error()
+}
+
+func newCSVReader(r io.Reader, opts CSVOptions) *csv.Reader {
+	reader := csv.NewReader(r)
+	if opts.Comma != 0 {
+		reader.Comma = opts.Comma
+	}
+	reader.Comment = opts.Comment
+	return reader
+}
+
+func newCSVWriter(w io.Writer, opts CSVOptions) *csv.Writer {
+	writer := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		writer.Comma = opts.Comma
+	}
+	return writer
+}
+
+// csvField is a struct field that maps to a CSV column.
+type csvField struct {
+	name  string
+	index int
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func csvFields(t reflect.Type) ([]csvField, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("CSV needs a struct type, not %v", t)
+	}
+
+	var fields []csvField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("csv"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, csvField{name: name, index: i})
+	}
+	return fields, nil
+}
+
+func (f *csvField) parse(v reflect.Value, s string) error {
+	field := v.Field(f.index)
+	if field.Kind() == reflect.Pointer {
+		if s == "" {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		field.Set(reflect.New(field.Type().Elem()))
+		field = field.Elem()
+	}
+
+	if field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %v", field.Type())
+	}
+	return nil
+}
+
+func (f *csvField) format(v reflect.Value) (string, error) {
+	field := v.Field(f.index)
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return "", nil
+		}
+		field = field.Elem()
+	}
+
+	if field.Type().Implements(textMarshalerType) {
+		text, err := field.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	if field.CanAddr() && field.Addr().Type().Implements(textMarshalerType) {
+		text, err := field.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		return field.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(field.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(field.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(field.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(field.Float(), 'g', -1, field.Type().Bits()), nil
+	default:
+		return "", fmt.Errorf("unsupported field type %v", field.Type())
+	}
+}
@@ -0,0 +1,139 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Nyeogmi/sahil-go/sahil"
+	"github.com/stretchr/testify/assert"
+)
+
+type roost struct {
+	Name     string   `csv:"name"`
+	Colony   int      `csv:"colony"`
+	Wingspan *float64 `csv:"wingspan"`
+	Notes    string   `csv:"-"`
+}
+
+func TestCSV(t *testing.T) {
+	input := "colony,name,wingspan,country\n" +
+		"20000000,Bracken Cave,,US\n" +
+		"1500000,Congress Avenue Bridge,0.3,US\n" +
+		"8000000,Kasanka,0.9,ZM\n"
+
+	src := CSV[roost](strings.NewReader(input), CSVOptions{})
+
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, roost{Name: "Bracken Cave", Colony: 20000000}, results[0])
+	assert.Equal(t, "Congress Avenue Bridge", results[1].Name)
+	assert.Equal(t, 0.3, *results[1].Wingspan)
+
+	results, err = src.Fetch(2)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "Kasanka", results[0].Name)
+	assert.True(t, src.Exhausted())
+}
+
+func TestCSVHeaderOption(t *testing.T) {
+	input := "Bracken Cave;20000000\nKasanka;8000000\n"
+
+	src := CSV[roost](strings.NewReader(input), CSVOptions{
+		Comma:  ';',
+		Header: []string{"name", "colony"},
+	})
+
+	results, err := src.Fetch(5)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []roost{
+		{Name: "Bracken Cave", Colony: 20000000},
+		{Name: "Kasanka", Colony: 8000000},
+	}, results)
+}
+
+func TestCSVStrict(t *testing.T) {
+	input := "name,country\nBracken Cave,US\n"
+
+	src := CSV[roost](strings.NewReader(input), CSVOptions{Strict: true})
+	_, err := src.Fetch(1)
+
+	var csvErr *CSVError
+	assert.True(t, errors.As(err, &csvErr))
+	assert.Equal(t, 2, csvErr.Column)
+	assert.Equal(t, "country", csvErr.Field)
+}
+
+func TestCSVErr(t *testing.T) {
+	input := "name,colony\n" +
+		"Bracken Cave,20000000\n" +
+		"Kasanka,lots\n"
+
+	src := CSV[roost](strings.NewReader(input), CSVOptions{})
+
+	results, err := src.Fetch(5)
+	assert.Equal(t, 0, len(results))
+
+	var csvErr *CSVError
+	assert.True(t, errors.As(err, &csvErr))
+	assert.Equal(t, 3, csvErr.Row)
+	assert.Equal(t, 3, csvErr.Line)
+	assert.Equal(t, 2, csvErr.Column)
+	assert.Equal(t, "colony", csvErr.Field)
+}
+
+func TestCSVRecords(t *testing.T) {
+	input := "name,colony\nBracken Cave,20000000\n\"Kasanka, Zambia\",8000000\n"
+
+	reader := &closingReader{Reader: strings.NewReader(input)}
+	src := CSVRecords(reader, CSVOptions{})
+
+	results, err := src.Fetch(5)
+	assert.Nil(t, err)
+	assert.EqualValues(t, [][]string{
+		{"name", "colony"},
+		{"Bracken Cave", "20000000"},
+		{"Kasanka, Zambia", "8000000"},
+	}, results)
+	assert.True(t, reader.closed)
+}
+
+func TestWriteCSV(t *testing.T) {
+	wingspan := 0.9
+	input := []roost{
+		{Name: "Bracken Cave", Colony: 20000000, Notes: "not written"},
+		{Name: "Kasanka, Zambia", Colony: 8000000, Wingspan: &wingspan},
+	}
+
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, sahil.Slice(input), 1, CSVOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "name,colony,wingspan\n"+
+		"Bracken Cave,20000000,\n"+
+		"\"Kasanka, Zambia\",8000000,0.9\n", buf.String())
+
+	// and back again
+	results, err := CSV[roost](&buf, CSVOptions{}).Fetch(5)
+	assert.Nil(t, err)
+	input[0].Notes = ""
+	assert.EqualValues(t, input, results)
+}
+
+func TestWriteCSVRecords(t *testing.T) {
+	input := [][]string{{"Bracken Cave", "US"}, {"Kasanka", "ZM"}}
+
+	var buf bytes.Buffer
+	err := WriteCSVRecords(&buf, sahil.Slice(input), 5, CSVOptions{Comma: '\t'})
+	assert.Nil(t, err)
+	assert.Equal(t, "Bracken Cave\tUS\nKasanka\tZM\n", buf.String())
+}
+
+func TestWriteCSVEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, sahil.Slice([]roost{}), 10, CSVOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "name,colony,wingspan\n", buf.String())
+}
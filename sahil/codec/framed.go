@@ -0,0 +1,157 @@
+package codec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"github.com/Nyeogmi/sahil-go/sahil"
+)
+
+// The framed format is a sequence of frames, each a uvarint of the payload
+// length plus one followed by the payload, then a trailer: a uvarint 0
+// followed by the big-endian CRC-32C of everything before it.
+
+// ErrTruncated means a framed stream ended before its trailer.
+var ErrTruncated = errors.New("framed stream is truncated")
+
+// ErrChecksum means a framed stream's contents don't match its checksum.
+var ErrChecksum = errors.New("framed stream failed its checksum")
+
+// maxFrameSize guards against allocating a huge buffer for a corrupt length.
+const maxFrameSize = 1 << 30
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+type framed[T any] struct {
+	source    io.Reader
+	reader    *bufio.Reader
+	unmarshal func([]byte) (T, error)
+	checksum  hash.Hash32
+	frame     int
+	done      bool
+}
+
+// Framed reads a stream of length-prefixed frames written by WriteFramed,
+// calling unmarshal on each frame to get an element.
+//
+// The stream ends with a checksum of its contents. Input that stops before
+// the checksum produces ErrTruncated, and input that doesn't match it
+// produces ErrChecksum. Since the checksum comes last, the elements before a
+// problem have already been produced by then.
+//
+// If r is an io.Closer, it is closed when the Paginated is closed, which
+// happens automatically once the input runs out or produces an error.
+func Framed[T any](r io.Reader, unmarshal func([]byte) (T, error)) sahil.Paginated[T] {
+	return sahil.FromSource[T](&framed[T]{
+		source:    r,
+		reader:    bufio.NewReader(r),
+		unmarshal: unmarshal,
+		checksum:  crc32.New(castagnoli),
+	})
+}
+
+func (f *framed[T]) Fetch(atLeast int) ([]T, error) {
+	var out []T
+	for len(out) < atLeast && !f.done {
+		size, err := binary.ReadUvarint(f.reader)
+		if err != nil {
+			return nil, truncated(err)
+		}
+		var prefix [binary.MaxVarintLen64]byte
+		f.checksum.Write(prefix[:binary.PutUvarint(prefix[:], size)])
+
+		if size == 0 {
+			if err := f.readTrailer(); err != nil {
+				return nil, err
+			}
+			f.done = true
+			break
+		}
+		if size-1 > maxFrameSize {
+			return nil, fmt.Errorf("frame %d: %d bytes is too large", f.frame, size-1)
+		}
+
+		payload := make([]byte, size-1)
+		if _, err := io.ReadFull(f.reader, payload); err != nil {
+			return nil, truncated(err)
+		}
+		f.checksum.Write(payload)
+
+		t, err := f.unmarshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", f.frame, err)
+		}
+		out = append(out, t)
+		f.frame += 1
+	}
+	return out, nil
+}
+
+func (f *framed[T]) readTrailer() error {
+	var trailer [4]byte
+	if _, err := io.ReadFull(f.reader, trailer[:]); err != nil {
+		return truncated(err)
+	}
+	if binary.BigEndian.Uint32(trailer[:]) != f.checksum.Sum32() {
+		return ErrChecksum
+	}
+	return nil
+}
+
+func (f *framed[T]) Close() error {
+	return closeReader(f.source)
+}
+
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	return err
+}
+
+// WriteFramed writes the elements of p to w as length-prefixed frames,
+// calling marshal on each element to get its frame. It fetches batchSize
+// elements at a time, and finishes with a checksum once p runs out, so a
+// stream cut short by a failed write can be told apart from a complete one.
+//
+// If writing fails, p is closed and the error is returned.
+func WriteFramed[T any](w io.Writer, p sahil.Paginated[T], batchSize int, marshal func(T) ([]byte, error)) error {
+	writer := bufio.NewWriter(w)
+	checksum := crc32.New(castagnoli)
+	out := io.MultiWriter(writer, checksum)
+
+	var prefix [binary.MaxVarintLen64]byte
+	err := drain(p, batchSize, func(batch []T) error {
+		for _, t := range batch {
+			payload, err := marshal(t)
+			if err != nil {
+				return err
+			}
+			if _, err := out.Write(prefix[:binary.PutUvarint(prefix[:], uint64(len(payload))+1)]); err != nil {
+				return err
+			}
+			if _, err := out.Write(payload); err != nil {
+				return err
+			}
+		}
+		return writer.Flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := out.Write(prefix[:binary.PutUvarint(prefix[:], 0)]); err != nil {
+		return err
+	}
+	var trailer [4]byte
+	binary.BigEndian.PutUint32(trailer[:], checksum.Sum32())
+	if _, err := writer.Write(trailer[:]); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
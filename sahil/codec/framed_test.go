@@ -0,0 +1,100 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Nyeogmi/sahil-go/sahil"
+	"github.com/stretchr/testify/assert"
+)
+
+func unmarshalBat(b []byte) (bat, error) {
+	var out bat
+	err := json.Unmarshal(b, &out)
+	return out, err
+}
+
+func marshalBat(b bat) ([]byte, error) {
+	return json.Marshal(b)
+}
+
+var vampires = []bat{
+	{"Desmodus", "rotundus"},
+	{"Diaemus", "youngi"},
+	{"Diphylla", "ecaudata"},
+}
+
+func TestFramed(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, WriteFramed(&buf, sahil.Slice(vampires), 2, marshalBat))
+
+	src := Framed(&buf, unmarshalBat)
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, vampires[:2], results)
+
+	results, err = src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, vampires[2:], results)
+	assert.True(t, src.Exhausted())
+}
+
+func TestFramedEmptyFrames(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteFramed(&buf, sahil.Slice([]string{"", "Desmodus", ""}), 5, func(s string) ([]byte, error) {
+		return []byte(s), nil
+	})
+	assert.Nil(t, err)
+
+	results, err := Framed(&buf, func(b []byte) (string, error) {
+		return string(b), nil
+	}).Fetch(5)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{"", "Desmodus", ""}, results)
+}
+
+func TestFramedTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, WriteFramed(&buf, sahil.Slice(vampires), 2, marshalBat))
+	full := buf.Bytes()
+
+	// every cut, including one between frames, is noticed
+	for n := 0; n < len(full); n++ {
+		src := Framed(bytes.NewReader(full[:n]), unmarshalBat)
+		var err error
+		for err == nil && !src.Exhausted() {
+			_, err = src.Fetch(1)
+		}
+		assert.ErrorIs(t, err, ErrTruncated, "cut at %d", n)
+	}
+}
+
+func TestFramedChecksum(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, WriteFramed(&buf, sahil.Slice(vampires), 2, marshalBat))
+
+	corrupt := bytes.Replace(buf.Bytes(), []byte("youngi"), []byte("youngu"), 1)
+	src := Framed(bytes.NewReader(corrupt), unmarshalBat)
+
+	results, err := src.Fetch(5)
+	assert.Equal(t, 0, len(results))
+	assert.ErrorIs(t, err, ErrChecksum)
+}
+
+func TestFramedUnmarshalErr(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, WriteFramed(&buf, sahil.Slice([]string{"Desmodus", "Diaemus"}), 5, func(s string) ([]byte, error) {
+		return []byte(s), nil
+	}))
+
+	_, err := Framed(&buf, func(b []byte) (string, error) {
+		if strings.HasPrefix(string(b), "Diae") {
+			return "", errors.New("NOT A VAMPIRE")
+		}
+		return string(b), nil
+	}).Fetch(5)
+	assert.EqualError(t, err, "frame 1: NOT A VAMPIRE")
+}
@@ -0,0 +1,63 @@
+package codec
+
+import (
+	"encoding/gob"
+	"io"
+
+	"github.com/Nyeogmi/sahil-go/sahil"
+)
+
+type gobSource[T any] struct {
+	source  io.Reader
+	decoder *gob.Decoder
+}
+
+// Gob reads a stream of gob-encoded values, such as one written by WriteGob.
+//
+// A stream cut off partway through a value produces io.ErrUnexpectedEOF, but
+// one cut off between values looks like a shorter stream. To catch that, use
+// Framed, which ends with a checksum.
+//
+// If r is an io.Closer, it is closed when the Paginated is closed, which
+// happens automatically once the input runs out or produces an error.
+func Gob[T any](r io.Reader) sahil.Paginated[T] {
+	return sahil.FromSource[T](&gobSource[T]{
+		source:  r,
+		decoder: gob.NewDecoder(r),
+	})
+}
+
+func (g *gobSource[T]) Fetch(atLeast int) ([]T, error) {
+	var out []T
+	for len(out) < atLeast {
+		var t T
+		err := g.decoder.Decode(&t)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+func (g *gobSource[T]) Close() error {
+	return closeReader(g.source)
+}
+
+// WriteGob writes the elements of p to w as a stream of gob-encoded values,
+// fetching batchSize elements at a time.
+//
+// If writing fails, p is closed and the error is returned.
+func WriteGob[T any](w io.Writer, p sahil.Paginated[T], batchSize int) error {
+	encoder := gob.NewEncoder(w)
+	return drain(p, batchSize, func(batch []T) error {
+		for i := range batch {
+			if err := encoder.Encode(&batch[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,39 @@
+package codec
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/Nyeogmi/sahil-go/sahil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGob(t *testing.T) {
+	input := []bat{
+		{"Desmodus", "rotundus"},
+		{"Diaemus", "youngi"},
+		{"Diphylla", "ecaudata"},
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteGob(&buf, sahil.Slice(input), 2))
+
+	src := Gob[bat](&buf)
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, input[:2], results)
+
+	results, err = src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, input[2:], results)
+	assert.True(t, src.Exhausted())
+}
+
+func TestGobTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, WriteGob(&buf, sahil.Slice([]bat{{"Desmodus", "rotundus"}}), 1))
+
+	_, err := Gob[bat](bytes.NewReader(buf.Bytes()[:buf.Len()-3])).Fetch(1)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
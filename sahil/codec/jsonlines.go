@@ -0,0 +1,105 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/Nyeogmi/sahil-go/sahil"
+)
+
+type jsonLines[T any] struct {
+	source io.Reader
+	reader *bufio.Reader
+	line   int
+}
+
+// JSONLines reads newline-delimited JSON, producing one element per line.
+//
+// Each Fetch decodes only as many lines as it was asked for. Blank lines are
+// skipped. A line that fails to decode ends the Paginated with a *LineError.
+//
+// If r is an io.Closer, it is closed when the Paginated is closed, which
+// happens automatically once the input runs out or produces an error.
+func JSONLines[T any](r io.Reader) sahil.Paginated[T] {
+	return sahil.FromSource[T](&jsonLines[T]{
+		source: r,
+		reader: bufio.NewReader(r),
+	})
+}
+
+func (j *jsonLines[T]) Fetch(atLeast int) ([]T, error) {
+	var out []T
+
+	for len(out) < atLeast {
+		raw, err := j.reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, &LineError{Line: j.line + 1, Err: err}
+		}
+		if len(raw) == 0 && err == io.EOF {
+			break
+		}
+		j.line += 1
+
+		if len(bytes.TrimSpace(raw)) > 0 {
+			var t T
+			if err := json.Unmarshal(raw, &t); err != nil {
+				return nil, &LineError{Line: j.line, Err: err}
+			}
+			out = append(out, t)
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (j *jsonLines[T]) Close() error {
+	return closeReader(j.source)
+}
+
+// WriteJSONLines writes the elements of p to w as newline-delimited JSON,
+// fetching batchSize elements at a time.
+//
+// If writing fails, p is closed and the error is returned.
+func WriteJSONLines[T any](w io.Writer, p sahil.Paginated[T], batchSize int) error {
+	bw := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bw)
+
+	return drain(p, batchSize, func(batch []T) error {
+		for i := range batch {
+			if err := encoder.Encode(&batch[i]); err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
+	})
+}
+
+// drain fetches batches from p until it runs out, calling write on each one.
+func drain[T any](p sahil.Paginated[T], batchSize int, write func([]T) error) error {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	for {
+		result := p.FetchBatch(batchSize)
+		if result.Err != nil {
+			return result.Err
+		}
+
+		if len(result.Items) > 0 {
+			if err := write(result.Items); err != nil {
+				p.Close()
+				return err
+			}
+		}
+
+		if result.Exhausted {
+			return nil
+		}
+	}
+}
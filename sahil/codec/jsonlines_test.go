@@ -0,0 +1,108 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Nyeogmi/sahil-go/sahil"
+	"github.com/stretchr/testify/assert"
+)
+
+type bat struct {
+	Genus   string `json:"genus"`
+	Species string `json:"species"`
+}
+
+func TestJSONLines(t *testing.T) {
+	input := `{"genus": "Desmodus", "species": "rotundus"}
+{"genus": "Diaemus", "species": "youngi"}
+
+{"genus": "Diphylla", "species": "ecaudata"}`
+
+	src := JSONLines[bat](strings.NewReader(input))
+
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []bat{
+		{"Desmodus", "rotundus"},
+		{"Diaemus", "youngi"},
+	}, results)
+
+	results, err = src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []bat{{"Diphylla", "ecaudata"}}, results)
+	assert.True(t, src.Exhausted())
+}
+
+func TestJSONLinesErr(t *testing.T) {
+	input := `{"genus": "Desmodus", "species": "rotundus"}
+{"genus": "Diaemus", "species": "youngi"}
+{"genus": "Diphylla", "species":`
+
+	src := JSONLines[bat](strings.NewReader(input))
+
+	results, err := src.Fetch(5)
+	assert.Equal(t, 0, len(results))
+
+	var lineErr *LineError
+	assert.True(t, errors.As(err, &lineErr))
+	assert.Equal(t, 3, lineErr.Line)
+}
+
+type closingReader struct {
+	*strings.Reader
+	closed bool
+}
+
+func (c *closingReader) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestJSONLinesClose(t *testing.T) {
+	r := &closingReader{Reader: strings.NewReader("1\n2\n3\n")}
+	src := JSONLines[int](r)
+
+	results, err := src.Fetch(3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 2, 3}, results)
+	assert.False(t, r.closed)
+
+	results, err = src.Fetch(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+	assert.True(t, r.closed)
+}
+
+func TestWriteJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteJSONLines(&buf, sahil.Slice([]bat{
+		{"Desmodus", "rotundus"},
+		{"Diaemus", "youngi"},
+		{"Diphylla", "ecaudata"},
+	}), 2)
+	assert.Nil(t, err)
+
+	assert.Equal(t, `{"genus":"Desmodus","species":"rotundus"}
+{"genus":"Diaemus","species":"youngi"}
+{"genus":"Diphylla","species":"ecaudata"}
+`, buf.String())
+
+	// and back again
+	results, err := JSONLines[bat](&buf).Fetch(5)
+	assert.Nil(t, err)
+	assert.Len(t, results, 3)
+}
+
+func TestWriteJSONLinesErr(t *testing.T) {
+	src := sahil.Func(func() (int, error) {
+		return 0, errors.New("SOURCE ERROR")
+	})
+
+	var buf bytes.Buffer
+	err := WriteJSONLines(&buf, src, 10)
+	assert.EqualError(t, err, "SOURCE ERROR")
+	assert.Equal(t, "", buf.String())
+}
@@ -0,0 +1,54 @@
+package sahil
+
+import (
+	"errors"
+)
+
+type cursor[T any, C any] struct {
+	position C
+	fn       func(C) ([]T, C, error)
+}
+
+// Cursor creates a Paginated from a data source that is read a page at a time
+// using a cursor, such as a keyset-paginated SQL query ("WHERE id > ? ORDER BY
+// id LIMIT 100") or an HTTP API that returns a next-page token.
+//
+// fn is called with the cursor for a page, starting from start, and returns
+// the elements on that page along with the cursor for the next page. It is
+// called until it produces an error of EOF (whose page is ignored) or an
+// empty page.
+//
+// Unlike SliceFunc, a Cursor can be checkpointed: its position is the cursor
+// for the next page it would read, so C must be a type that encoding/gob can
+// encode.
+func Cursor[T any, C any](start C, fn func(C) ([]T, C, error)) Paginated[T] {
+	return wrap[T](&cursor[T, C]{position: start, fn: fn})
+}
+
+func (c *cursor[T, C]) Fetch(atLeast int) ([]T, error) {
+	var out []T
+
+	for len(out) < atLeast {
+		page, next, err := c.fn(c.position)
+		if errors.Is(err, EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			break
+		}
+
+		out = append(out, page...)
+		c.position = next
+	}
+	return out, nil
+}
+
+func (c *cursor[T, C]) Checkpoint() ([]byte, error) {
+	return encodeCheckpoint(&c.position)
+}
+
+func (c *cursor[T, C]) Restore(checkpoint []byte) error {
+	return decodeCheckpoint(checkpoint, &c.position)
+}
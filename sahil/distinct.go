@@ -0,0 +1,190 @@
+package sahil
+
+import (
+	"container/list"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// keySet remembers keys that have been seen before.
+//
+// add returns true if the key is new, and false if it is (or might be) a repeat.
+type keySet[K any] interface {
+	add(key K) bool
+}
+
+// Distinct drops elements of a Paginated that have already appeared earlier in
+// the stream.
+//
+// Distinct remembers every element it has seen, so its memory use grows with
+// the number of distinct elements. For streams too large for that, see
+// DistinctByWindow and DistinctByBloom.
+//
+// Like Filter, Distinct fetches larger batches from the underlying Paginated
+// based on the proportion of elements admitted so far. See MapWindowed for
+// more documentation on this behavior.
+//
+// None of the Distinct variants can be checkpointed, since the keys they
+// remember aren't saved.
+func Distinct[A comparable](p Paginated[A]) Paginated[A] {
+	return DistinctBy(p, func(a A) (A, error) { return a, nil })
+}
+
+// DistinctBy drops elements of a Paginated whose key has already appeared
+// earlier in the stream. The first element with each key is kept.
+//
+// DistinctBy remembers every key it has seen.
+func DistinctBy[A any, K comparable](
+	p Paginated[A],
+	keyFn func(A) (K, error),
+) Paginated[A] {
+	return distinctBy[A, K](p, keyFn, mapSet[K]{})
+}
+
+// DistinctByWindow is DistinctBy with bounded memory: it only remembers the
+// `size` most recently seen keys.
+//
+// A repeated key is dropped only if it was seen recently enough to still be
+// in the window, so repeats that are far apart in the stream may get through.
+// Seeing a key again counts as a use, keeping it in the window longer.
+func DistinctByWindow[A any, K comparable](
+	p Paginated[A],
+	keyFn func(A) (K, error),
+	size int,
+) Paginated[A] {
+	if size < 1 {
+		size = 1
+	}
+	return distinctBy[A, K](p, keyFn, &lruSet[K]{
+		size:     size,
+		order:    list.New(),
+		elements: map[K]*list.Element{},
+	})
+}
+
+// DistinctByBloom is DistinctBy with bounded memory: it remembers keys using a
+// Bloom filter sized for `expected` distinct keys.
+//
+// A Bloom filter never lets a repeated key through, but can mistake a new key
+// for a repeat and drop it. fpRate is the chance of that happening for each
+// element, assuming no more than `expected` distinct keys are seen. Past that
+// point the actual rate climbs.
+func DistinctByBloom[A any](
+	p Paginated[A],
+	keyFn func(A) (string, error),
+	expected int,
+	fpRate float64,
+) Paginated[A] {
+	return distinctBy[A, string](p, keyFn, newBloomSet(expected, fpRate))
+}
+
+func distinctBy[A any, K any](
+	p Paginated[A],
+	keyFn func(A) (K, error),
+	seen keySet[K],
+) Paginated[A] {
+	fn := func(as []A) ([]A, error) {
+		var out []A
+		for _, a := range as {
+			key, err := keyFn(a)
+			if err != nil {
+				return nil, err
+			}
+			if seen.add(key) {
+				out = append(out, a)
+			}
+		}
+		return out, nil
+	}
+
+	// this is MapWindowed, except that fn remembers what it has seen, so
+	// checkpointing it would lose track of the keys
+	return wrap[A](&mapWindowed[A, A]{
+		underlying: p,
+		fn:         fn,
+		stateful:   true,
+	})
+}
+
+type mapSet[K comparable] map[K]struct{}
+
+func (s mapSet[K]) add(key K) bool {
+	if _, ok := s[key]; ok {
+		return false
+	}
+	s[key] = struct{}{}
+	return true
+}
+
+type lruSet[K comparable] struct {
+	size     int
+	order    *list.List // front is most recently used
+	elements map[K]*list.Element
+}
+
+func (s *lruSet[K]) add(key K) bool {
+	if el, ok := s.elements[key]; ok {
+		s.order.MoveToFront(el)
+		return false
+	}
+
+	s.elements[key] = s.order.PushFront(key)
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.elements, oldest.Value.(K))
+	}
+	return true
+}
+
+type bloomSet struct {
+	bits    []uint64
+	nBits   uint64
+	nHashes int
+}
+
+func newBloomSet(expected int, fpRate float64) *bloomSet {
+	if expected < 1 {
+		expected = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.01
+	}
+
+	// standard sizing: m = -n ln(p) / ln(2)^2, k = (m / n) ln(2)
+	n := float64(expected)
+	m := math.Ceil(-n * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	k := int(math.Round(m / n * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	nBits := uint64(m)
+	return &bloomSet{
+		bits:    make([]uint64, (nBits+63)/64),
+		nBits:   nBits,
+		nHashes: k,
+	}
+}
+
+func (s *bloomSet) add(key string) bool {
+	h := fnv.New128a()
+	h.Write([]byte(key))
+	sum := h.Sum(nil)
+
+	// double hashing: derive all k indices from two independent halves
+	h1 := binary.BigEndian.Uint64(sum[:8])
+	h2 := binary.BigEndian.Uint64(sum[8:]) | 1
+
+	isNew := false
+	for i := 0; i < s.nHashes; i++ {
+		bit := (h1 + uint64(i)*h2) % s.nBits
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if s.bits[word]&mask == 0 {
+			isNew = true
+			s.bits[word] |= mask
+		}
+	}
+	return isNew
+}
@@ -0,0 +1,99 @@
+package sahil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistinct(t *testing.T) {
+	src := Distinct(Slice([]int{1, 2, 2, 3, 1, 4, 3, 5, 5, 6}))
+
+	// the exact number of results
+	// depends on how MapWindowed estimates how many it needs
+	results, err := src.Fetch(3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 2, 3, 4}, results)
+
+	results, err = src.Fetch(5)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{5, 6}, results)
+
+	results, err = src.Fetch(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+}
+
+func TestDistinctBy(t *testing.T) {
+	src := DistinctBy(
+		Slice([]string{
+			"Desmodus rotundus",
+			"Diaemus youngi",
+			"Desmodus draculae",
+			"Diphylla ecaudata",
+		}),
+		func(s string) (string, error) {
+			return strings.Split(s, " ")[0], nil
+		},
+	)
+
+	results, err := src.Fetch(4)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{"Desmodus rotundus", "Diaemus youngi", "Diphylla ecaudata"}, results)
+}
+
+func TestDistinctByErr(t *testing.T) {
+	src := DistinctBy(
+		Slice([]int{1, 2, 3, 4, 5}),
+		func(x int) (int, error) {
+			if x == 4 {
+				return 0, errors.New("DUPLICATE ERROR")
+			}
+			return x, nil
+		},
+	)
+
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 2}, results)
+
+	results, err = src.Fetch(3)
+	assert.Equal(t, 0, len(results))
+	assert.EqualError(t, err, "DUPLICATE ERROR")
+}
+
+func TestDistinctByWindow(t *testing.T) {
+	identity := func(x int) (int, error) { return x, nil }
+
+	// 1 is still in the window the second time, but not the third time
+	src := DistinctByWindow(Slice([]int{1, 2, 1, 3, 4, 1}), identity, 2)
+
+	results, err := src.Fetch(10)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 2, 3, 4, 1}, results)
+}
+
+func TestDistinctByBloom(t *testing.T) {
+	var input []int
+	for i := 0; i < 1000; i++ {
+		input = append(input, i, i)
+	}
+
+	src := DistinctByBloom(Slice(input), func(x int) (string, error) {
+		return fmt.Sprint(x), nil
+	}, 1000, 0.01)
+
+	results, err := src.Fetch(2000)
+	assert.Nil(t, err)
+
+	// no repeats can get through, and few new elements should be dropped
+	seen := map[int]bool{}
+	for _, r := range results {
+		assert.False(t, seen[r])
+		seen[r] = true
+	}
+	assert.Greater(t, len(results), 950)
+}
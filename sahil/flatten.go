@@ -1,5 +1,7 @@
 package sahil
 
+import "fmt"
+
 type flatten[T any] struct {
 	source    Paginated[Paginated[T]]
 	buf       []Paginated[T]
@@ -7,12 +9,20 @@ type flatten[T any] struct {
 	pagErr    error
 }
 
+type concat[T any] struct {
+	ps   []Paginated[T]
+	done int
+}
+
 // Concat takes a slice of Paginated and combines them into a single Paginated.
 //
-// Concat(ps...) is equivalent to Flatten(Slice(ps)), but may be implemented more
-// efficiently in practice. (Currently, it is not.)
+// Concat(ps...) is equivalent to Flatten(Slice(ps)), but is implemented more
+// directly. Closing the result closes every Paginated in ps that hasn't
+// already run out.
 func Concat[T any](ps ...Paginated[T]) Paginated[T] {
-	return Flatten(Slice(ps))
+	myPs := make([]Paginated[T], len(ps))
+	copy(myPs, ps)
+	return wrap[T](&concat[T]{ps: myPs})
 }
 
 // Flatten takes a Paginated of Paginated and combines them into a single Paginated.
@@ -22,6 +32,9 @@ func Concat[T any](ps ...Paginated[T]) Paginated[T] {
 //
 // For instance, Flatten(Slice([]Paginated[int] { Slice(1, 2, 3), Slice(4, 5, 6) }))
 // is equivalent to Slice(1, 2, 3, 4, 5, 6).
+//
+// Closing the result closes the source, along with any Paginated already
+// fetched from it that haven't run out.
 func Flatten[T any](p Paginated[Paginated[T]]) Paginated[T] {
 	return wrap[T](&flatten[T]{
 		source: p,
@@ -30,25 +43,33 @@ func Flatten[T any](p Paginated[Paginated[T]]) Paginated[T] {
 }
 
 func (j *flatten[T]) Fetch(atLeast int) ([]T, error) {
+	out, _, err := j.fetchWithin(atLeast, deadline{})
+	return out, err
+}
+
+func (j *flatten[T]) fetchWithin(atLeast int, d deadline) ([]T, bool, error) {
 	var out []T
 
 	for {
 		if len(out) >= atLeast {
-			return out, nil
+			return out, false, nil
+		}
+		if d.near(0) {
+			return out, true, nil
 		}
 
 		current, err := j.currentPaginated()
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 
 		if current == nil {
-			return out, nil
+			return out, false, nil
 		}
 
-		buf, err := current.Fetch(atLeast - len(out))
+		buf, timedOut, err := current.fetchWithin(atLeast-len(out), d)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 
 		if out == nil {
@@ -56,6 +77,10 @@ func (j *flatten[T]) Fetch(atLeast int) ([]T, error) {
 		} else {
 			out = append(out, buf...)
 		}
+
+		if timedOut {
+			return out, true, nil
+		}
 	}
 }
 
@@ -69,32 +94,124 @@ func (j *flatten[T]) currentPaginated() (*Paginated[T], error) {
 	}
 
 	for {
-		if len(j.buf) == 0 {
-			nextPaginators, err := j.source.Fetch(1)
-
-			if err == nil {
-				j.buf = nextPaginators
-			} else {
-				j.buf = nil
-				j.pagErr = err
-				return nil, j.pagErr
-			}
+		// drop anything that has run out, rather than fetching from it again
+		for len(j.buf) > 0 && j.buf[0].Exhausted() {
+			j.buf = j.buf[1:]
 		}
-
-		for len(j.buf) > 0 {
+		if len(j.buf) > 0 {
 			next := j.buf[0]
-
-			if *next.isExhausted {
-				j.buf = j.buf[1:]
-			}
-
 			return &next, nil
 		}
 
-		if *j.source.isExhausted {
+		if j.source.Exhausted() {
 			// Shortcut to this path so we can get here without trying and failing to find another one
 			j.exhausted = true
 			return nil, nil
 		}
+
+		nextPaginators, err := j.source.Fetch(1)
+		if err != nil {
+			j.buf = nil
+			j.pagErr = err
+			return nil, j.pagErr
+		}
+		j.buf = nextPaginators
+	}
+}
+
+func (j *flatten[T]) Close() error {
+	var errs []error
+	for _, p := range j.buf {
+		errs = append(errs, p.Close())
+	}
+	j.buf = nil
+	errs = append(errs, j.source.Close())
+	return closeAll(errs...)
+}
+
+func (c *concat[T]) Fetch(atLeast int) ([]T, error) {
+	out, _, err := c.fetchWithin(atLeast, deadline{})
+	return out, err
+}
+
+func (c *concat[T]) fetchWithin(atLeast int, d deadline) ([]T, bool, error) {
+	var out []T
+
+	for len(out) < atLeast && len(c.ps) > 0 {
+		if c.ps[0].Exhausted() {
+			// it ran out before we got to it, so don't fetch from it
+			c.ps = c.ps[1:]
+			c.done += 1
+			continue
+		}
+		if d.near(0) {
+			return out, true, nil
+		}
+
+		buf, timedOut, err := c.ps[0].fetchWithin(atLeast-len(out), d)
+		if err != nil {
+			return nil, false, err
+		}
+		out = append(out, buf...)
+
+		if c.ps[0].Exhausted() {
+			c.ps = c.ps[1:]
+			c.done += 1
+		} else if timedOut {
+			return out, true, nil
+		}
+	}
+	return out, false, nil
+}
+
+func (c *concat[T]) Close() error {
+	var errs []error
+	for _, p := range c.ps {
+		errs = append(errs, p.Close())
+	}
+	c.ps = nil
+	return closeAll(errs...)
+}
+
+type concatCheckpoint struct {
+	Done    int // how many of the Paginated have been used up
+	Current []byte
+}
+
+func (c *concat[T]) Checkpoint() ([]byte, error) {
+	saved := concatCheckpoint{Done: c.done}
+	if len(c.ps) > 0 {
+		current, err := c.ps[0].Checkpoint()
+		if err != nil {
+			return nil, err
+		}
+		saved.Current = current
+	}
+	return encodeCheckpoint(saved)
+}
+
+func (c *concat[T]) Restore(checkpoint []byte) error {
+	var saved concatCheckpoint
+	if err := decodeCheckpoint(checkpoint, &saved); err != nil {
+		return err
+	}
+	if saved.Done > len(c.ps) {
+		return fmt.Errorf("checkpoint is at Paginated %d of %d", saved.Done, len(c.ps))
+	}
+
+	// the ones we already got through won't be used
+	var errs []error
+	for _, p := range c.ps[:saved.Done] {
+		errs = append(errs, p.Close())
+	}
+	if err := closeAll(errs...); err != nil {
+		return err
+	}
+	c.ps = c.ps[saved.Done:]
+	c.done = saved.Done
+
+	if len(c.ps) > 0 {
+		return c.ps[0].restore(saved.Current)
 	}
+	return nil
 }
@@ -62,3 +62,74 @@ func TestConcatErr(t *testing.T) {
 	assert.Equal(t, 0, len(results))
 	assert.EqualError(t, err, "BAT ERROR")
 }
+
+func TestConcatClose(t *testing.T) {
+	closed := 0
+	src := Concat(
+		Slice([]int{1, 2}).OnClose(closeCounter(&closed, nil)),
+		Slice([]int{3, 4}).OnClose(closeCounter(&closed, nil)),
+		Slice([]int{5, 6}).OnClose(closeCounter(&closed, nil)),
+	)
+
+	results, err := src.Fetch(3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 2, 3}, results)
+	assert.Equal(t, 1, closed)
+
+	assert.Nil(t, src.Close())
+	assert.Equal(t, 3, closed)
+}
+
+func TestFlattenClose(t *testing.T) {
+	closed := 0
+	src := FlatMap(Slice([]int{1, 2, 3}), func(x int) (Paginated[int], error) {
+		return Slice([]int{x, x, x}).OnClose(closeCounter(&closed, nil)), nil
+	})
+
+	results, err := src.Fetch(4)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 1, 1, 2}, results)
+
+	assert.Nil(t, src.Close())
+	assert.Equal(t, 2, closed)
+}
+
+func TestFlattenNoLateFetches(t *testing.T) {
+	var late []int
+	watched := func(p Paginated[int]) Paginated[int] {
+		return FromSource[int](&lateSpy[int]{p: p, late: &late})
+	}
+
+	src := Flatten(Slice([]Paginated[int]{
+		watched(Slice([]int{1, 2})),
+		watched(Slice([]int{3, 4})),
+	}))
+	results, err := src.Fetch(10)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 2, 3, 4}, results)
+	assert.Empty(t, late)
+}
+
+func TestConcatNoLateFetches(t *testing.T) {
+	var late []int
+	done := FromSource[int](&lateSpy[int]{p: Slice([]int{1}), late: &late})
+	_, err := done.Fetch(2)
+	assert.Nil(t, err)
+
+	src := Concat(done, Slice([]int{2, 3}))
+	results, err := src.Fetch(5)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{2, 3}, results)
+	assert.Empty(t, late)
+}
+
+// lateSpy passes p through, logging the atLeast of every fetch from its
+// Paginated after that Paginated is exhausted.
+type lateSpy[T any] struct {
+	p    Paginated[T]
+	late *[]int
+}
+
+func (s *lateSpy[T]) Fetch(atLeast int) ([]T, error) { return s.p.Fetch(atLeast) }
+func (s *lateSpy[T]) Close() error                   { return s.p.Close() }
+func (s *lateSpy[T]) LateFetch(atLeast int)          { *s.late = append(*s.late, atLeast) }
@@ -26,6 +26,9 @@ func Func[T any](fn func() (T, error)) Paginated[T] {
 //
 // Repeatedly producing an empty slice may cause the caller to loop
 // infinitely in search of an element.
+//
+// If fn reads from something that needs to be released, such as a database
+// cursor, use OnClose to release it.
 func SliceFunc[T any](fn func() ([]T, error)) Paginated[T] {
 	return FlatMap(Func(fn), func(ts []T) (Paginated[T], error) {
 		return Slice(ts), nil
@@ -33,16 +36,25 @@ func SliceFunc[T any](fn func() ([]T, error)) Paginated[T] {
 }
 
 func (f *fetchFunc[T]) Fetch(atLeast int) ([]T, error) {
+	out, _, err := f.fetchWithin(atLeast, deadline{})
+	return out, err
+}
+
+func (f *fetchFunc[T]) fetchWithin(atLeast int, d deadline) ([]T, bool, error) {
 	var out []T
 
 	for len(out) < atLeast {
+		if d.near(0) {
+			return out, true, nil
+		}
+
 		t, err := f.fn()
 		if errors.Is(err, EOF) {
 			break
 		} else if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		out = append(out, t)
 	}
-	return out, nil
+	return out, false, nil
 }
@@ -0,0 +1,123 @@
+package sahil
+
+import (
+	"math"
+)
+
+// Group is a run of adjacent elements from a Paginated that share a key.
+type Group[K any, T any] struct {
+	Key      K
+	Elements []T
+}
+
+type groupAdjacent[A any, K any] struct {
+	underlying Paginated[A]
+	keyFn      func(A) (K, error)
+	same       func(K, K) (bool, error)
+	pending    *Group[K, A]
+	lastKey    K
+	nIn, nOut  int
+}
+
+// GroupAdjacent collects runs of adjacent elements with the same key into
+// groups.
+//
+// This is meant for input that is already sorted by key, such as rows paged
+// out of a table ordered by parent id. Elements with the same key that are
+// not adjacent end up in separate groups.
+//
+// A group is never split across Fetch calls: GroupAdjacent holds back the
+// trailing group of each batch until it sees an element with a different key
+// or the underlying Paginated runs out.
+//
+// GroupAdjacent estimates how many input elements it needs per group based on
+// the groups it has produced so far, the same way MapWindowed does.
+func GroupAdjacent[A any, K comparable](
+	p Paginated[A],
+	keyFn func(A) (K, error),
+) Paginated[Group[K, A]] {
+	return wrap[Group[K, A]](&groupAdjacent[A, K]{
+		underlying: p,
+		keyFn:      keyFn,
+		same: func(k1, k2 K) (bool, error) {
+			return k1 == k2, nil
+		},
+	})
+}
+
+// ChunkBy splits a Paginated into runs of adjacent elements.
+//
+// pred is called on each pair of neighboring elements. If it returns true,
+// both elements go in the same chunk. Otherwise, the second element starts a
+// new chunk.
+//
+// Like GroupAdjacent, ChunkBy never splits a chunk across Fetch calls.
+func ChunkBy[A any](
+	p Paginated[A],
+	pred func(A, A) (bool, error),
+) Paginated[[]A] {
+	groups := wrap[Group[A, A]](&groupAdjacent[A, A]{
+		underlying: p,
+		keyFn:      func(a A) (A, error) { return a, nil },
+		same:       pred,
+	})
+	return Map(groups, func(g Group[A, A]) ([]A, error) {
+		return g.Elements, nil
+	})
+}
+
+func (g *groupAdjacent[A, K]) Fetch(atLeast int) ([]Group[K, A], error) {
+	var out []Group[K, A]
+
+	for len(out) < atLeast {
+		if g.underlying.Exhausted() {
+			break
+		}
+
+		// the trailing group is always held back, so ask for at least one
+		// element more than we think we need
+		proportion := float64(g.nIn+1.0) / float64(g.nOut+1.0)
+		nWanted := int(math.Ceil(float64(atLeast-len(out))*proportion)) + 1
+
+		input, err := g.underlying.Fetch(nWanted)
+		if err != nil {
+			return nil, err
+		}
+		g.nIn += len(input)
+
+		for _, a := range input {
+			key, err := g.keyFn(a)
+			if err != nil {
+				return nil, err
+			}
+
+			if g.pending != nil {
+				same, err := g.same(g.lastKey, key)
+				if err != nil {
+					return nil, err
+				}
+				if same {
+					g.pending.Elements = append(g.pending.Elements, a)
+					g.lastKey = key
+					continue
+				}
+				out = append(out, *g.pending)
+			}
+
+			g.pending = &Group[K, A]{Key: key, Elements: []A{a}}
+			g.lastKey = key
+		}
+	}
+
+	if g.underlying.Exhausted() && g.pending != nil {
+		out = append(out, *g.pending)
+		g.pending = nil
+	}
+
+	g.nOut += len(out)
+	return out, nil
+}
+
+func (g *groupAdjacent[A, K]) Close() error {
+	return g.underlying.Close()
+}
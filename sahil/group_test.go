@@ -0,0 +1,90 @@
+package sahil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type batRow struct {
+	roost int
+	name  string
+}
+
+func TestGroupAdjacent(t *testing.T) {
+	src := GroupAdjacent(
+		Slice([]batRow{
+			{1, "Desmodus"},
+			{1, "Diaemus"},
+			{2, "Diphylla"},
+			{3, "Pteropus"},
+			{3, "Rousettus"},
+			{3, "Eidolon"},
+		}),
+		func(r batRow) (int, error) { return r.roost, nil },
+	)
+
+	// the exact number of results depends on how many elements
+	// GroupAdjacent estimates it needs, which is implementation-defined
+	results, err := src.Fetch(1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []Group[int, batRow]{
+		{Key: 1, Elements: []batRow{{1, "Desmodus"}, {1, "Diaemus"}}},
+		{Key: 2, Elements: []batRow{{2, "Diphylla"}}},
+	}, results)
+
+	// roost 3 is only complete once the source runs out
+	results, err = src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []Group[int, batRow]{
+		{Key: 3, Elements: []batRow{{3, "Pteropus"}, {3, "Rousettus"}, {3, "Eidolon"}}},
+	}, results)
+
+	results, err = src.Fetch(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+}
+
+func TestGroupAdjacentNoSplit(t *testing.T) {
+	// one giant group should come out whole, however small the fetches
+	input := make([]int, 50)
+	src := GroupAdjacent(Slice(input), func(x int) (int, error) { return x, nil })
+
+	results, err := src.Fetch(1)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Len(t, results[0].Elements, 50)
+}
+
+func TestGroupAdjacentErr(t *testing.T) {
+	src := GroupAdjacent(
+		Slice([]int{1, 1, 2, 2, 2, 2, 3, 3}),
+		func(x int) (int, error) {
+			if x == 3 {
+				return 0, errors.New("ROOST ERROR")
+			}
+			return x, nil
+		},
+	)
+
+	results, err := src.Fetch(1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []Group[int, int]{{Key: 1, Elements: []int{1, 1}}}, results)
+
+	results, err = src.Fetch(1)
+	assert.Equal(t, 0, len(results))
+	assert.EqualError(t, err, "ROOST ERROR")
+}
+
+func TestChunkBy(t *testing.T) {
+	// split into ascending runs
+	src := ChunkBy(
+		Slice([]int{1, 2, 3, 2, 5, 1, 1}),
+		func(a, b int) (bool, error) { return a < b, nil },
+	)
+
+	results, err := src.Fetch(4)
+	assert.Nil(t, err)
+	assert.EqualValues(t, [][]int{{1, 2, 3}, {2, 5}, {1}, {1}}, results)
+}
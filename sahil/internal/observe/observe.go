@@ -0,0 +1,10 @@
+// Package observe lets sahiltest watch how a Paginated is used, without
+// adding anything to sahil's public API for it.
+package observe
+
+// LateFetchObserver is implemented by a Source that wants to know when its
+// Paginated is fetched from after it is exhausted. The Paginated answers
+// those fetches itself, so they never reach the Source's Fetch.
+type LateFetchObserver interface {
+	LateFetch(atLeast int)
+}
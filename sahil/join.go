@@ -0,0 +1,202 @@
+package sahil
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Pair is an element of one Paginated matched with an element of another.
+type Pair[A any, B any] struct {
+	Left  A
+	Right B
+}
+
+// ErrJoinTooLarge is produced when the side of a join that has to be held in
+// memory turns out to be bigger than the ceiling it was given.
+var ErrJoinTooLarge = errors.New("join side is too large to hold in memory")
+
+// joinBuildBatchSize is how many elements a join fetches at a time while
+// reading the side it holds in memory.
+const joinBuildBatchSize = 1024
+
+// InnerJoin matches the elements of two Paginated by key, producing a Pair
+// for every combination of left and right elements with equal keys.
+//
+// The right side is read completely into a hash table the first time the
+// join is fetched from, so it should be the smaller of the two. If it has
+// more than maxRight elements, the join fails with ErrJoinTooLarge. A
+// maxRight of 0 or less means there is no limit.
+//
+// The left side is streamed in batches. Pairs come out in the order of the
+// left side, then in the order of the right side for each left element.
+//
+// Because each left element can match any number of right elements, the join
+// estimates how many left elements it needs the same way MapWindowed does.
+//
+// Closing the join closes both sides.
+func InnerJoin[L any, R any, K comparable](
+	left Paginated[L],
+	right Paginated[R],
+	leftKey func(L) (K, error),
+	rightKey func(R) (K, error),
+	maxRight int,
+) Paginated[Pair[L, R]] {
+	table := newJoinTable(right, rightKey, maxRight)
+	return MapWindowed(left, func(ls []L) ([]Pair[L, R], error) {
+		var out []Pair[L, R]
+		err := probeJoin(table, ls, leftKey, func(l L, rs []R) {
+			for _, r := range rs {
+				out = append(out, Pair[L, R]{Left: l, Right: r})
+			}
+		})
+		return out, err
+	}).OnClose(right.Close)
+}
+
+// LeftJoin is InnerJoin, except that left elements with no match are kept,
+// paired with a nil Right.
+func LeftJoin[L any, R any, K comparable](
+	left Paginated[L],
+	right Paginated[R],
+	leftKey func(L) (K, error),
+	rightKey func(R) (K, error),
+	maxRight int,
+) Paginated[Pair[L, *R]] {
+	table := newJoinTable(right, rightKey, maxRight)
+	return MapWindowed(left, func(ls []L) ([]Pair[L, *R], error) {
+		var out []Pair[L, *R]
+		err := probeJoin(table, ls, leftKey, func(l L, rs []R) {
+			if len(rs) == 0 {
+				out = append(out, Pair[L, *R]{Left: l, Right: nil})
+			}
+			for _, r := range rs {
+				r := r // each Pair gets its own copy, not a pointer into the table
+				out = append(out, Pair[L, *R]{Left: l, Right: &r})
+			}
+		})
+		return out, err
+	}).OnClose(right.Close)
+}
+
+// SemiJoin keeps the left elements that have at least one match on the right
+// side. Each left element appears at most once, however many matches it has.
+//
+// See InnerJoin for how the two sides are read.
+func SemiJoin[L any, R any, K comparable](
+	left Paginated[L],
+	right Paginated[R],
+	leftKey func(L) (K, error),
+	rightKey func(R) (K, error),
+	maxRight int,
+) Paginated[L] {
+	table := newJoinTable(right, rightKey, maxRight)
+	return MapWindowed(left, func(ls []L) ([]L, error) {
+		var out []L
+		err := probeJoin(table, ls, leftKey, func(l L, rs []R) {
+			if len(rs) > 0 {
+				out = append(out, l)
+			}
+		})
+		return out, err
+	}).OnClose(right.Close)
+}
+
+// AntiJoin keeps the left elements that have no match on the right side.
+//
+// See InnerJoin for how the two sides are read.
+func AntiJoin[L any, R any, K comparable](
+	left Paginated[L],
+	right Paginated[R],
+	leftKey func(L) (K, error),
+	rightKey func(R) (K, error),
+	maxRight int,
+) Paginated[L] {
+	table := newJoinTable(right, rightKey, maxRight)
+	return MapWindowed(left, func(ls []L) ([]L, error) {
+		var out []L
+		err := probeJoin(table, ls, leftKey, func(l L, rs []R) {
+			if len(rs) == 0 {
+				out = append(out, l)
+			}
+		})
+		return out, err
+	}).OnClose(right.Close)
+}
+
+// joinTable is the in-memory side of a hash join. It is built lazily.
+type joinTable[R any, K comparable] struct {
+	source   Paginated[R]
+	keyFn    func(R) (K, error)
+	maxRight int
+	built    bool
+	rows     map[K][]R
+}
+
+func newJoinTable[R any, K comparable](
+	source Paginated[R],
+	keyFn func(R) (K, error),
+	maxRight int,
+) *joinTable[R, K] {
+	return &joinTable[R, K]{source: source, keyFn: keyFn, maxRight: maxRight}
+}
+
+func (j *joinTable[R, K]) build() error {
+	j.built = true
+	j.rows = map[K][]R{}
+
+	count := 0
+	for {
+		nWanted := joinBuildBatchSize
+		if j.maxRight > 0 && nWanted > j.maxRight+1-count {
+			// just enough to notice going over the limit
+			nWanted = j.maxRight + 1 - count
+		}
+
+		batch, err := j.source.FetchRange(nWanted, nWanted)
+		if err != nil {
+			return err
+		}
+
+		count += len(batch)
+		if j.maxRight > 0 && count > j.maxRight {
+			j.rows = nil
+			return fmt.Errorf("%w: more than %d elements", ErrJoinTooLarge, j.maxRight)
+		}
+
+		for _, r := range batch {
+			key, err := j.keyFn(r)
+			if err != nil {
+				return err
+			}
+			j.rows[key] = append(j.rows[key], r)
+		}
+
+		if len(batch) < nWanted {
+			return nil
+		}
+	}
+}
+
+// probeJoin looks up the matches for each left element, in order, building
+// the table first if needed.
+func probeJoin[L any, R any, K comparable](
+	j *joinTable[R, K],
+	ls []L,
+	leftKey func(L) (K, error),
+	emit func(L, []R),
+) error {
+	if !j.built {
+		if err := j.build(); err != nil {
+			return err
+		}
+	}
+
+	for _, l := range ls {
+		key, err := leftKey(l)
+		if err != nil {
+			return err
+		}
+		emit(l, j.rows[key])
+	}
+	return nil
+}
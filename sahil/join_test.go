@@ -0,0 +1,130 @@
+package sahil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type joinBat struct {
+	name  string
+	roost int
+}
+
+type joinRoost struct {
+	id   int
+	cave string
+}
+
+var joinBats = []joinBat{
+	{"Desmodus", 1},
+	{"Diaemus", 2},
+	{"Diphylla", 3},
+	{"Pteropus", 1},
+}
+
+var joinRoosts = []joinRoost{
+	{1, "Cueva del Guácharo"},
+	{1, "Bracken Cave"},
+	{2, "Deer Cave"},
+}
+
+func batRoost(b joinBat) (int, error)  { return b.roost, nil }
+func roostId(r joinRoost) (int, error) { return r.id, nil }
+func batNames(bs []joinBat) (out []string) {
+	for _, b := range bs {
+		out = append(out, b.name)
+	}
+	return out
+}
+
+func TestInnerJoin(t *testing.T) {
+	src := InnerJoin(Slice(joinBats), Slice(joinRoosts), batRoost, roostId, 0)
+
+	results, err := src.Fetch(10)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []Pair[joinBat, joinRoost]{
+		{joinBats[0], joinRoosts[0]},
+		{joinBats[0], joinRoosts[1]},
+		{joinBats[1], joinRoosts[2]},
+		{joinBats[3], joinRoosts[0]},
+		{joinBats[3], joinRoosts[1]},
+	}, results)
+}
+
+func TestLeftJoin(t *testing.T) {
+	src := LeftJoin(Slice(joinBats), Slice(joinRoosts), batRoost, roostId, 0)
+
+	results, err := src.Fetch(10)
+	assert.Nil(t, err)
+	assert.Len(t, results, 6)
+	assert.Equal(t, joinBats[2], results[3].Left)
+	assert.Nil(t, results[3].Right)
+	assert.Equal(t, joinRoosts[2], *results[2].Right)
+}
+
+func TestLeftJoinRightIsACopy(t *testing.T) {
+	roosts := append([]joinRoost(nil), joinRoosts...)
+	src := LeftJoin(Slice(joinBats), Slice(roosts), batRoost, roostId, 0)
+
+	first, err := src.Fetch(1)
+	assert.Nil(t, err)
+	first[0].Right.cave = "Batcave"
+
+	rest, err := src.Fetch(10)
+	assert.Nil(t, err)
+	last := rest[len(rest)-2]
+	assert.Equal(t, joinBats[3], last.Left)
+	assert.Equal(t, joinRoosts[0], *last.Right)
+	assert.EqualValues(t, joinRoosts, roosts)
+}
+
+func TestSemiJoin(t *testing.T) {
+	src := SemiJoin(Slice(joinBats), Slice(joinRoosts), batRoost, roostId, 0)
+
+	results, err := src.Fetch(10)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{"Desmodus", "Diaemus", "Pteropus"}, batNames(results))
+}
+
+func TestAntiJoin(t *testing.T) {
+	src := AntiJoin(Slice(joinBats), Slice(joinRoosts), batRoost, roostId, 0)
+
+	results, err := src.Fetch(10)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{"Diphylla"}, batNames(results))
+}
+
+func TestJoinTooLarge(t *testing.T) {
+	src := InnerJoin(Slice(joinBats), Slice(joinRoosts), batRoost, roostId, 2)
+
+	results, err := src.Fetch(1)
+	assert.Equal(t, 0, len(results))
+	assert.True(t, errors.Is(err, ErrJoinTooLarge))
+
+	// exactly at the limit is fine
+	src = InnerJoin(Slice(joinBats), Slice(joinRoosts), batRoost, roostId, 3)
+	results, err = src.Fetch(1)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, results)
+}
+
+func TestJoinErr(t *testing.T) {
+	src := InnerJoin(
+		Slice(joinBats),
+		Slice(joinRoosts),
+		func(b joinBat) (int, error) {
+			if b.name == "Diphylla" {
+				return 0, errors.New("NO ROOST")
+			}
+			return b.roost, nil
+		},
+		roostId,
+		0,
+	)
+
+	results, err := src.Fetch(10)
+	assert.Equal(t, 0, len(results))
+	assert.EqualError(t, err, "NO ROOST")
+}
@@ -0,0 +1,55 @@
+package sahil
+
+import (
+	"bufio"
+	"io"
+)
+
+type scanTokens struct {
+	scanner *bufio.Scanner
+	closer  io.Closer
+}
+
+// Lines creates a Paginated of the lines of r, without their line endings.
+//
+// If r is an io.Closer, such as an *os.File, it is closed when the Paginated
+// is closed, which happens automatically once r runs out or produces an
+// error.
+//
+// Lines longer than bufio.MaxScanTokenSize produce bufio.ErrTooLong. To read
+// those, use Scanner with a bigger buffer.
+func Lines(r io.Reader) Paginated[string] {
+	closer, _ := r.(io.Closer)
+	return wrap[string](&scanTokens{scanner: bufio.NewScanner(r), closer: closer})
+}
+
+// Scanner creates a Paginated of the tokens produced by a bufio.Scanner, such
+// as words with bufio.ScanWords. Any error from the scanner is propagated to
+// the Fetch caller.
+//
+// Scanner doesn't close the scanner's reader. If it needs to be closed, use
+// OnClose.
+func Scanner(s *bufio.Scanner) Paginated[string] {
+	return wrap[string](&scanTokens{scanner: s})
+}
+
+func (s *scanTokens) Fetch(atLeast int) ([]string, error) {
+	var out []string
+	for len(out) < atLeast {
+		if !s.scanner.Scan() {
+			if err := s.scanner.Err(); err != nil {
+				return nil, err
+			}
+			break
+		}
+		out = append(out, s.scanner.Text())
+	}
+	return out, nil
+}
+
+func (s *scanTokens) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
@@ -0,0 +1,77 @@
+package sahil
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type closingReader struct {
+	*strings.Reader
+	closed int
+}
+
+func (c *closingReader) Close() error {
+	c.closed += 1
+	return nil
+}
+
+func TestLines(t *testing.T) {
+	r := &closingReader{Reader: strings.NewReader("Myotis\r\nEptesicus\n\nLasiurus")}
+	src := Lines(r)
+
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{"Myotis", "Eptesicus"}, results)
+	assert.Equal(t, 0, r.closed)
+
+	results, err = src.Fetch(5)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{"", "Lasiurus"}, results)
+	assert.True(t, src.Exhausted())
+	assert.Equal(t, 1, r.closed)
+
+	assert.Nil(t, src.Close())
+	assert.Equal(t, 1, r.closed)
+}
+
+func TestLinesClose(t *testing.T) {
+	r := &closingReader{Reader: strings.NewReader("Myotis\nEptesicus\nLasiurus\n")}
+	src := Lines(r)
+
+	results, err := src.Fetch(1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{"Myotis"}, results)
+
+	assert.Nil(t, src.Close())
+	assert.Equal(t, 1, r.closed)
+}
+
+func TestScanner(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("Myotis lucifugus  Myotis\tsodalis\n"))
+	scanner.Split(bufio.ScanWords)
+
+	results, err := Scanner(scanner).Fetch(10)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{"Myotis", "lucifugus", "Myotis", "sodalis"}, results)
+}
+
+func TestScannerErr(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("Myotis\n" + strings.Repeat("a", 100) + "\n"))
+	scanner.Buffer(nil, 16)
+	src := Scanner(scanner)
+
+	results, err := src.Fetch(1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{"Myotis"}, results)
+
+	results, err = src.Fetch(1)
+	assert.Equal(t, 0, len(results))
+	assert.True(t, errors.Is(err, bufio.ErrTooLong))
+
+	_, err = src.Fetch(1)
+	assert.True(t, errors.Is(err, bufio.ErrTooLong))
+}
@@ -13,18 +13,35 @@ func Map[A any, B any](p Paginated[A], fn func(A) (B, error)) Paginated[B] {
 }
 
 func (m *mapFn[A, B]) Fetch(atLeast int) ([]B, error) {
-	outA, err := m.underlying.Fetch(atLeast)
+	outB, _, err := m.fetchWithin(atLeast, deadline{})
+	return outB, err
+}
+
+func (m *mapFn[A, B]) fetchWithin(atLeast int, d deadline) ([]B, bool, error) {
+	outA, timedOut, err := m.underlying.fetchWithin(atLeast, d)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	outB := make([]B, len(outA))
 	for i, a := range outA {
 		b, err := m.fn(a)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		outB[i] = b
 	}
-	return outB, nil
+	return outB, timedOut, nil
+}
+
+func (m *mapFn[A, B]) Close() error {
+	return m.underlying.Close()
+}
+
+func (m *mapFn[A, B]) Checkpoint() ([]byte, error) {
+	return m.underlying.Checkpoint()
+}
+
+func (m *mapFn[A, B]) Restore(checkpoint []byte) error {
+	return m.underlying.restore(checkpoint)
 }
@@ -1,12 +1,16 @@
 package sahil
 
 import (
+	"fmt"
 	"math"
+	"time"
 )
 
 type mapWindowed[A any, B any] struct {
 	underlying Paginated[A]
 	fn         func([]A) ([]B, error)
+	limiter    *Limiter
+	stateful   bool // fn remembers earlier windows, so can't be checkpointed
 	nIn, nOut  int
 }
 
@@ -30,6 +34,9 @@ type mapWindowed[A any, B any] struct {
 // The current implementation uses a bunch of heuristics that made practical
 // sense at my job, but those heuristics aren't set in stone.
 //
+// MapWindowed can be checkpointed, on the assumption that fn doesn't remember
+// anything between calls.
+//
 func MapWindowed[A any, B any](
 	p Paginated[A],
 	fn func([]A) ([]B, error),
@@ -42,13 +49,38 @@ func MapWindowed[A any, B any](
 	})
 }
 
+// MapWindowedLimited is MapWindowed, except that calls to fn are rate-limited.
+//
+// Each call to fn counts as one call and len(window) elements against the
+// limiter, and waits until the limiter allows it.
+func MapWindowedLimited[A any, B any](
+	p Paginated[A],
+	fn func([]A) ([]B, error),
+	limiter *Limiter,
+) Paginated[B] {
+	return wrap[B](&mapWindowed[A, B]{
+		underlying: p,
+		fn:         fn,
+		limiter:    limiter,
+	})
+}
+
 // used when calling down to our source
 const pessimismFactorSmall = 1.2 // 20% more than we think we need
 const pessimismFactorBig = 1.5   // 50% more than we think we need
 
 func (m *mapWindowed[A, B]) Fetch(atLeast int) ([]B, error) {
+	results, _, err := m.fetchWithin(atLeast, deadline{})
+	return results, err
+}
+
+func (m *mapWindowed[A, B]) fetchWithin(atLeast int, d deadline) ([]B, bool, error) {
 	var results []B
 
+	// how long the last batch took, to guess whether there's time for another
+	var lastBatch time.Duration
+	batches := 0
+
 	// take at most 10 batches to get everything
 	// (to avoid the results just trickling in towards the end of input)
 	var smallestFetchAllowed = int(atLeast / 10)
@@ -57,6 +89,15 @@ func (m *mapWindowed[A, B]) Fetch(atLeast int) ([]B, error) {
 	}
 
 	for {
+		if m.underlying.Exhausted() {
+			// the last call used up the input, so there's nothing to ask for
+			return results, false, nil
+		}
+		if batches > 0 && d.near(lastBatch) {
+			return results, true, nil
+		}
+		batchStart := d.now()
+
 		proportion := float64(m.nOut+1.0) / float64(m.nIn+1.0)
 
 		optimisticInput := float64(atLeast) / proportion
@@ -67,14 +108,20 @@ func (m *mapWindowed[A, B]) Fetch(atLeast int) ([]B, error) {
 			atLeastInput = float64(smallestFetchAllowed)
 		}
 
-		input, err := m.underlying.FetchRange(int(atLeastInput), int(math.Ceil(atMostInput)))
+		input, timedOut, err := m.underlying.fetchRangeWithin(int(atLeastInput), int(math.Ceil(atMostInput)), d)
 		if err != nil {
-			return nil, err
+			return nil, false, err
+		}
+
+		if m.limiter != nil && len(input) > 0 {
+			if err := m.limiter.reserve(len(input)); err != nil {
+				return nil, false, err
+			}
 		}
 
 		output, err := m.fn(input)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 
 		m.nIn += len(input)
@@ -85,8 +132,43 @@ func (m *mapWindowed[A, B]) Fetch(atLeast int) ([]B, error) {
 			results = append(results, output...)
 		}
 
-		if len(results) >= atLeast || *m.underlying.isExhausted {
-			return results, nil
+		if len(results) >= atLeast || m.underlying.Exhausted() {
+			return results, false, nil
+		}
+		if timedOut {
+			return results, true, nil
 		}
+		lastBatch = d.now().Sub(batchStart)
+		batches += 1
+	}
+}
+
+func (m *mapWindowed[A, B]) Close() error {
+	return m.underlying.Close()
+}
+
+type mapWindowedCheckpoint struct {
+	NIn, NOut  int
+	Underlying []byte
+}
+
+func (m *mapWindowed[A, B]) Checkpoint() ([]byte, error) {
+	if m.stateful {
+		return nil, fmt.Errorf("%w: %T keeps state between windows", ErrNotCheckpointable, m)
+	}
+
+	underlying, err := m.underlying.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	return encodeCheckpoint(mapWindowedCheckpoint{NIn: m.nIn, NOut: m.nOut, Underlying: underlying})
+}
+
+func (m *mapWindowed[A, B]) Restore(checkpoint []byte) error {
+	var saved mapWindowedCheckpoint
+	if err := decodeCheckpoint(checkpoint, &saved); err != nil {
+		return err
 	}
+	m.nIn, m.nOut = saved.NIn, saved.NOut
+	return m.underlying.restore(saved.Underlying)
 }
@@ -0,0 +1,183 @@
+package sahil
+
+type mergeSide[T any] struct {
+	source Paginated[T]
+	buf    []T
+}
+
+type mergeJoin[L any, R any] struct {
+	left      mergeSide[L]
+	right     mergeSide[R]
+	cmp       func(L, R) int
+	keepLeft  bool
+	keepRight bool
+}
+
+// MergeJoin matches the elements of two Paginated that are both sorted by the
+// same key, producing a Pair for every combination of left and right elements
+// with equal keys.
+//
+// cmp compares the keys of a left and a right element, returning a negative
+// number if the left key sorts first, a positive number if the right key sorts
+// first, and zero if they are equal. Both sides must be sorted in the order
+// cmp describes, or matches will be missed.
+//
+// Unlike InnerJoin, MergeJoin doesn't hold either side in memory: it walks both
+// sides in lockstep, fetching batches from whichever side is behind. Only a
+// run of elements with the same key is buffered. Duplicate keys are allowed on
+// both sides.
+func MergeJoin[L any, R any](
+	left Paginated[L],
+	right Paginated[R],
+	cmp func(L, R) int,
+) Paginated[Pair[L, R]] {
+	return Map(newMergeJoin(left, right, cmp, false, false), func(p Pair[*L, *R]) (Pair[L, R], error) {
+		return Pair[L, R]{Left: *p.Left, Right: *p.Right}, nil
+	})
+}
+
+// MergeLeftJoin is MergeJoin, except that left elements with no match are
+// kept, paired with a nil Right.
+func MergeLeftJoin[L any, R any](
+	left Paginated[L],
+	right Paginated[R],
+	cmp func(L, R) int,
+) Paginated[Pair[L, *R]] {
+	return Map(newMergeJoin(left, right, cmp, true, false), func(p Pair[*L, *R]) (Pair[L, *R], error) {
+		return Pair[L, *R]{Left: *p.Left, Right: p.Right}, nil
+	})
+}
+
+// MergeOuterJoin is MergeJoin, except that unmatched elements from both sides
+// are kept. An unmatched left element is paired with a nil Right, and an
+// unmatched right element is paired with a nil Left.
+func MergeOuterJoin[L any, R any](
+	left Paginated[L],
+	right Paginated[R],
+	cmp func(L, R) int,
+) Paginated[Pair[*L, *R]] {
+	return newMergeJoin(left, right, cmp, true, true)
+}
+
+func newMergeJoin[L any, R any](
+	left Paginated[L],
+	right Paginated[R],
+	cmp func(L, R) int,
+	keepLeft bool,
+	keepRight bool,
+) Paginated[Pair[*L, *R]] {
+	return wrap[Pair[*L, *R]](&mergeJoin[L, R]{
+		left:      mergeSide[L]{source: left},
+		right:     mergeSide[R]{source: right},
+		cmp:       cmp,
+		keepLeft:  keepLeft,
+		keepRight: keepRight,
+	})
+}
+
+// fill makes sure at least n elements are buffered, unless the source runs
+// out. It fetches at least batchSize elements at a time.
+func (s *mergeSide[T]) fill(n int, batchSize int) error {
+	for len(s.buf) < n && !s.source.Exhausted() {
+		nWanted := n - len(s.buf)
+		if nWanted < batchSize {
+			nWanted = batchSize
+		}
+		batch, err := s.source.Fetch(nWanted)
+		if err != nil {
+			return err
+		}
+		s.buf = append(s.buf, batch...)
+	}
+	return nil
+}
+
+// run returns the number of buffered elements at the start of the side that
+// match, fetching more as needed.
+func (s *mergeSide[T]) run(batchSize int, matches func(T) bool) (int, error) {
+	n := 1
+	for {
+		if err := s.fill(n+1, batchSize); err != nil {
+			return 0, err
+		}
+		if n >= len(s.buf) || !matches(s.buf[n]) {
+			return n, nil
+		}
+		n += 1
+	}
+}
+
+func (m *mergeJoin[L, R]) Fetch(atLeast int) ([]Pair[*L, *R], error) {
+	var out []Pair[*L, *R]
+
+	for len(out) < atLeast {
+		if err := m.left.fill(1, atLeast); err != nil {
+			return nil, err
+		}
+		if err := m.right.fill(1, atLeast); err != nil {
+			return nil, err
+		}
+
+		nLeft, nRight := len(m.left.buf), len(m.right.buf)
+		if nLeft == 0 && (nRight == 0 || !m.keepRight) {
+			break
+		}
+		if nRight == 0 && !m.keepLeft {
+			break
+		}
+
+		var c int
+		switch {
+		case nLeft == 0:
+			c = 1
+		case nRight == 0:
+			c = -1
+		default:
+			c = m.cmp(m.left.buf[0], m.right.buf[0])
+		}
+
+		if c < 0 {
+			if m.keepLeft {
+				l := m.left.buf[0]
+				out = append(out, Pair[*L, *R]{Left: &l})
+			}
+			m.left.buf = m.left.buf[1:]
+			continue
+		}
+
+		if c > 0 {
+			if m.keepRight {
+				r := m.right.buf[0]
+				out = append(out, Pair[*L, *R]{Right: &r})
+			}
+			m.right.buf = m.right.buf[1:]
+			continue
+		}
+
+		// both sides may have several elements with this key
+		l0, r0 := m.left.buf[0], m.right.buf[0]
+		nLeft, err := m.left.run(atLeast, func(l L) bool { return m.cmp(l, r0) == 0 })
+		if err != nil {
+			return nil, err
+		}
+		nRight, err = m.right.run(atLeast, func(r R) bool { return m.cmp(l0, r) == 0 })
+		if err != nil {
+			return nil, err
+		}
+
+		for _, l := range m.left.buf[:nLeft] {
+			for _, r := range m.right.buf[:nRight] {
+				l, r := l, r
+				out = append(out, Pair[*L, *R]{Left: &l, Right: &r})
+			}
+		}
+		m.left.buf = m.left.buf[nLeft:]
+		m.right.buf = m.right.buf[nRight:]
+	}
+
+	return out, nil
+}
+
+func (m *mergeJoin[L, R]) Close() error {
+	return closeAll(m.left.source.Close(), m.right.source.Close())
+}
@@ -0,0 +1,112 @@
+package sahil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func cmpInts(l, r int) int {
+	return l - r
+}
+
+func TestMergeJoin(t *testing.T) {
+	src := MergeJoin(
+		Slice([]int{1, 2, 2, 4, 5, 7}),
+		Slice([]int{2, 2, 3, 5, 6, 7, 8}),
+		cmpInts,
+	)
+
+	results, err := src.Fetch(10)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []Pair[int, int]{
+		{2, 2}, {2, 2}, {2, 2}, {2, 2},
+		{5, 5},
+		{7, 7},
+	}, results)
+}
+
+func TestMergeJoinSmallBatches(t *testing.T) {
+	// a run of duplicates longer than any single fetch
+	left := Slice([]int{1, 1, 1, 1, 1, 2})
+	right := Slice([]int{0, 1, 1, 1, 2, 2})
+	src := MergeJoin(left, right, cmpInts)
+
+	var results []Pair[int, int]
+	for {
+		batch, err := src.Fetch(1)
+		assert.Nil(t, err)
+		results = append(results, batch...)
+		if len(batch) < 1 {
+			break
+		}
+	}
+
+	assert.Len(t, results, 5*3+2)
+}
+
+func TestMergeLeftJoin(t *testing.T) {
+	src := MergeLeftJoin(
+		Slice([]int{1, 2, 4}),
+		Slice([]int{2, 3}),
+		cmpInts,
+	)
+
+	results, err := src.Fetch(10)
+	assert.Nil(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, 1, results[0].Left)
+	assert.Nil(t, results[0].Right)
+	assert.Equal(t, 2, results[1].Left)
+	assert.Equal(t, 2, *results[1].Right)
+	assert.Equal(t, 4, results[2].Left)
+	assert.Nil(t, results[2].Right)
+}
+
+func TestMergeOuterJoin(t *testing.T) {
+	src := MergeOuterJoin(
+		Slice([]int{1, 2, 4}),
+		Slice([]int{2, 3, 5}),
+		cmpInts,
+	)
+
+	results, err := src.Fetch(10)
+	assert.Nil(t, err)
+
+	var flat [][2]int
+	for _, p := range results {
+		pair := [2]int{-1, -1}
+		if p.Left != nil {
+			pair[0] = *p.Left
+		}
+		if p.Right != nil {
+			pair[1] = *p.Right
+		}
+		flat = append(flat, pair)
+	}
+	assert.EqualValues(t, [][2]int{{1, -1}, {2, 2}, {-1, 3}, {4, -1}, {-1, 5}}, flat)
+}
+
+func TestMergeJoinErr(t *testing.T) {
+	i := 0
+	src := MergeJoin(
+		Func(func() (int, error) {
+			i += 1
+			if i > 3 {
+				return 0, errors.New("MERGE ERROR")
+			}
+			return i, nil
+		}),
+		Slice([]int{1, 2, 3, 4, 5}),
+		cmpInts,
+	)
+
+	results, err := src.Fetch(1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []Pair[int, int]{{1, 1}}, results)
+
+	results, err = src.Fetch(5)
+	assert.Equal(t, 0, len(results))
+	assert.EqualError(t, err, "MERGE ERROR")
+}
@@ -0,0 +1,219 @@
+package sahil
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pipelineBuilder turns a string of bytes into a random pipeline of Slice,
+// Func, Map, Filter, MapWindowed, Flatten and Concat, along with a naive
+// model of what it should produce.
+type pipelineBuilder struct {
+	data   []byte
+	nextID int
+	late   []int // fetches made by a stage after its input was exhausted
+}
+
+func (b *pipelineBuilder) next() int {
+	if len(b.data) == 0 {
+		return 0
+	}
+	out := int(b.data[0])
+	b.data = b.data[1:]
+	return out
+}
+
+func (b *pipelineBuilder) leafValues() []int {
+	out := make([]int, b.next()%20)
+	for i := range out {
+		out[i] = b.nextID
+		b.nextID += 1
+	}
+	return out
+}
+
+// watch passes p through unchanged, logging any fetch from it after it is
+// exhausted in b.late.
+func watch[T any](b *pipelineBuilder, p Paginated[T]) Paginated[T] {
+	return FromSource[T](&lateSpy[T]{p: p, late: &b.late})
+}
+
+// child builds part of a pipeline to feed into a stage, watched for late
+// fetches.
+func (b *pipelineBuilder) child(depth int) (Paginated[int], []int) {
+	p, want := b.build(depth)
+	return watch(b, p), want
+}
+
+func (b *pipelineBuilder) build(depth int) (Paginated[int], []int) {
+	op := b.next() % 7
+	if depth >= 4 {
+		op %= 2
+	}
+
+	switch op {
+	case 0:
+		values := b.leafValues()
+		return Slice(values), append([]int{}, values...)
+
+	case 1:
+		values := b.leafValues()
+		i := 0
+		return Func(func() (int, error) {
+			if i == len(values) {
+				return 0, EOF
+			}
+			i += 1
+			return values[i-1], nil
+		}), append([]int{}, values...)
+
+	case 2:
+		p, want := b.child(depth + 1)
+		for i := range want {
+			want[i] = want[i]*2 + 1
+		}
+		return Map(p, func(x int) (int, error) { return x*2 + 1, nil }), want
+
+	case 3:
+		k := b.next()%4 + 2
+		p, model := b.child(depth + 1)
+		var want []int
+		for _, x := range model {
+			if x%k != 0 {
+				want = append(want, x)
+			}
+		}
+		return Filter(p, func(x int) (bool, error) { return x%k != 0, nil }), want
+
+	case 4:
+		p, model := b.child(depth + 1)
+		var want []int
+		for _, x := range model {
+			for i := 0; i < x%3; i++ {
+				want = append(want, x)
+			}
+		}
+		return MapWindowed(p, func(xs []int) ([]int, error) {
+			var out []int
+			for _, x := range xs {
+				for i := 0; i < x%3; i++ {
+					out = append(out, x)
+				}
+			}
+			return out, nil
+		}), want
+
+	case 5:
+		var ps []Paginated[int]
+		var want []int
+		for n := b.next() % 4; n > 0; n-- {
+			p, model := b.child(depth + 1)
+			ps = append(ps, p)
+			want = append(want, model...)
+		}
+		return Flatten(watch(b, Slice(ps))), want
+
+	default:
+		p1, want1 := b.child(depth + 1)
+		p2, want2 := b.child(depth + 1)
+		return Concat(p1, p2), append(want1, want2...)
+	}
+}
+
+// checkPipeline builds the pipeline described by data, then fetches from it
+// in the batch sizes described by the rest of data.
+func checkPipeline(t *testing.T, data []byte) {
+	b := &pipelineBuilder{data: data}
+	p, want := b.build(0)
+	if len(want) > 1000 {
+		// deep pipelines fetched one element at a time are slow, and don't
+		// tell us much more than smaller ones
+		t.Skip("pipeline is too big")
+	}
+
+	var got []int
+	for step := 0; ; step++ {
+		atLeast := b.next()%8 + 1
+
+		var results []int
+		var err error
+		var atMost int
+		if b.next()%2 == 0 {
+			atMost = 2 * atLeast
+			results, err = p.Fetch(atLeast)
+		} else {
+			atMost = atLeast + b.next()%4
+			results, err = p.FetchRange(atLeast, atMost)
+		}
+
+		if err != nil {
+			t.Fatalf("step %d: unexpected error %v", step, err)
+		}
+		if len(results) > atMost {
+			t.Fatalf("step %d: asked for %d to %d elements, got %d", step, atLeast, atMost, len(results))
+		}
+		got = append(got, results...)
+
+		if len(results) < atLeast {
+			if !p.Exhausted() {
+				t.Fatalf("step %d: short fetch of %d, but not exhausted", step, len(results))
+			}
+			break
+		}
+		if step > len(want) {
+			t.Fatalf("pipeline produced %d elements after %d steps, but the model only has %d", len(got), step, len(want))
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		results, err := p.Fetch(1)
+		if len(results) > 0 || err != nil {
+			t.Fatalf("fetch after exhaustion produced %v, %v", results, err)
+		}
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("produced %v, but the model produced %v", got, want)
+	}
+	if len(b.late) > 0 {
+		t.Fatalf("stages fetched from exhausted inputs %d times", len(b.late))
+	}
+}
+
+func TestPipelineProperties(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		data := make([]byte, rng.Intn(200))
+		rng.Read(data)
+
+		ok := t.Run(fmt.Sprint(i), func(t *testing.T) {
+			checkPipeline(t, data)
+		})
+		if !ok {
+			t.Logf("failing input: %q", data)
+			break
+		}
+	}
+}
+
+func TestPipelineModel(t *testing.T) {
+	// Map(Slice), then Concat(Filter(Slice), Func)
+	b := &pipelineBuilder{data: []byte{2, 0, 3, 6, 3, 2, 0, 4, 1, 2}}
+	_, want := b.build(0)
+	assert.EqualValues(t, []int{1, 3, 5}, want)
+
+	_, want = b.build(0)
+	assert.EqualValues(t, []int{3, 5, 6, 7, 8}, want)
+}
+
+func FuzzPipeline(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{6, 4, 0, 10, 5, 3, 0, 7, 1, 3, 2, 19, 1, 1, 3, 7, 0, 2, 1, 5})
+	f.Add([]byte{5, 3, 4, 1, 19, 3, 2, 0, 12, 6, 0, 4, 1, 9, 8, 1, 0, 0, 7, 1})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		checkPipeline(t, data)
+	})
+}
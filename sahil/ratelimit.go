@@ -0,0 +1,173 @@
+package sahil
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time used by a Limiter.
+//
+// The default clock is the system clock. Tests can provide their own to avoid
+// actually sleeping.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Limiter caps how quickly a pipeline can hit an upstream service, in calls
+// per second and elements per second.
+//
+// Both limits are token buckets that hold up to one second's worth of tokens,
+// so a Limiter that has been idle allows a short burst. A single Limiter can
+// be shared by several stages (or goroutines) to cap their combined rate.
+type Limiter struct {
+	ctx      context.Context
+	clock    Clock
+	mutex    sync.Mutex
+	calls    tokenBucket
+	elements tokenBucket
+}
+
+type tokenBucket struct {
+	rate   float64 // tokens per second, or 0 for no limit
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a Limiter allowing callsPerSecond calls and
+// elementsPerSecond elements per second. A rate of 0 or less means that
+// dimension is not limited.
+//
+// Waiting for the Limiter is abandoned once ctx is done, and the stage that
+// was waiting fails with ctx.Err(). A nil clock means the system clock.
+func NewLimiter(
+	ctx context.Context,
+	callsPerSecond float64,
+	elementsPerSecond float64,
+	clock Clock,
+) *Limiter {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	now := clock.Now()
+	return &Limiter{
+		ctx:      ctx,
+		clock:    clock,
+		calls:    newTokenBucket(callsPerSecond, now),
+		elements: newTokenBucket(elementsPerSecond, now),
+	}
+}
+
+func newTokenBucket(rate float64, now time.Time) tokenBucket {
+	if rate < 0 {
+		rate = 0
+	}
+	return tokenBucket{rate: rate, tokens: burst(rate), last: now}
+}
+
+func burst(rate float64) float64 {
+	if rate < 1 {
+		return 1
+	}
+	return rate
+}
+
+// take removes n tokens, possibly going into debt, and returns how long the
+// caller has to wait for the debt to be paid off.
+func (b *tokenBucket) take(n float64, now time.Time) time.Duration {
+	if b.rate == 0 {
+		return 0
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > burst(b.rate) {
+		b.tokens = burst(b.rate)
+	}
+	b.last = now
+
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// reserve accounts for one call processing `elements` elements, then waits
+// until the Limiter allows it to go ahead.
+func (l *Limiter) reserve(elements int) error {
+	l.mutex.Lock()
+	now := l.clock.Now()
+	delay := l.calls.take(1, now)
+	if elementDelay := l.elements.take(float64(elements), now); elementDelay > delay {
+		delay = elementDelay
+	}
+	l.mutex.Unlock()
+
+	if err := l.ctx.Err(); err != nil {
+		return err
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	select {
+	case <-l.ctx.Done():
+		return l.ctx.Err()
+	case <-l.clock.After(delay):
+		return nil
+	}
+}
+
+// charge accounts for elements after the fact. Future calls wait until the
+// debt is paid off.
+func (l *Limiter) charge(elements int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.elements.take(float64(elements), l.clock.Now())
+}
+
+type rateLimit[T any] struct {
+	underlying Paginated[T]
+	limiter    *Limiter
+}
+
+// RateLimit caps how quickly elements are fetched from a Paginated.
+//
+// Each fetch from the underlying Paginated counts as one call. Since the
+// number of elements isn't known until the fetch returns, elements are
+// counted afterwards: a large batch makes the following fetch wait longer.
+func RateLimit[T any](p Paginated[T], limiter *Limiter) Paginated[T] {
+	return wrap[T](&rateLimit[T]{underlying: p, limiter: limiter})
+}
+
+func (r *rateLimit[T]) Fetch(atLeast int) ([]T, error) {
+	if err := r.limiter.reserve(0); err != nil {
+		return nil, err
+	}
+
+	out, err := r.underlying.Fetch(atLeast)
+	if err != nil {
+		return nil, err
+	}
+
+	r.limiter.charge(len(out))
+	return out, nil
+}
+
+func (r *rateLimit[T]) Close() error {
+	return r.underlying.Close()
+}
+
+func (r *rateLimit[T]) Checkpoint() ([]byte, error) {
+	return r.underlying.Checkpoint()
+}
+
+func (r *rateLimit[T]) Restore(checkpoint []byte) error {
+	return r.underlying.restore(checkpoint)
+}
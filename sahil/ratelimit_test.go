@@ -0,0 +1,119 @@
+package sahil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock moves time forward whenever something waits on it, instead of
+// sleeping.
+type fakeClock struct {
+	now   time.Time
+	waits []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.waits = append(c.waits, d)
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestRateLimitCalls(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	limiter := NewLimiter(context.Background(), 2, 0, clock)
+
+	i := 0
+	src := RateLimit(Func(func() (int, error) {
+		i += 1
+		return i, nil
+	}), limiter)
+
+	// the first two calls fit in the burst
+	for n := 0; n < 4; n++ {
+		results, err := src.Fetch(1)
+		assert.Nil(t, err)
+		assert.Len(t, results, 1)
+	}
+
+	assert.EqualValues(t, []time.Duration{
+		500 * time.Millisecond,
+		500 * time.Millisecond,
+	}, clock.waits)
+}
+
+func TestRateLimitElements(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	limiter := NewLimiter(context.Background(), 0, 10, clock)
+
+	var input []int
+	for i := 0; i < 100; i++ {
+		input = append(input, i)
+	}
+	src := RateLimit(Slice(input), limiter)
+
+	// 10 elements is the burst, then 20 elements leaves us 2 seconds in debt
+	_, err := src.Fetch(10)
+	assert.Nil(t, err)
+	_, err = src.Fetch(20)
+	assert.Nil(t, err)
+	assert.Empty(t, clock.waits)
+
+	_, err = src.Fetch(1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []time.Duration{2 * time.Second}, clock.waits)
+}
+
+func TestRateLimitCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	limiter := NewLimiter(ctx, 1, 0, &fakeClock{now: time.Unix(0, 0)})
+	src := RateLimit(Slice([]int{1, 2, 3}), limiter)
+
+	results, err := src.Fetch(1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1}, results)
+
+	cancel()
+	results, err = src.Fetch(1)
+	assert.Equal(t, 0, len(results))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestMapWindowedLimited(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	limiter := NewLimiter(context.Background(), 1, 0, clock)
+
+	calls := 0
+	src := MapWindowedLimited(
+		Slice([]int{1, 2, 3, 4, 5, 6}),
+		func(xs []int) ([]int, error) {
+			if len(xs) > 0 {
+				calls += 1
+			}
+			return xs, nil
+		},
+		limiter,
+	)
+
+	for {
+		results, err := src.Fetch(1)
+		assert.Nil(t, err)
+		if len(results) < 1 {
+			break
+		}
+	}
+
+	// every call with work to do after the first waited a full second
+	assert.Len(t, clock.waits, calls-1)
+	for _, w := range clock.waits {
+		assert.Equal(t, time.Second, w)
+	}
+}
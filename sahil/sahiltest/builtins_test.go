@@ -0,0 +1,295 @@
+package sahiltest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/Nyeogmi/sahil-go/sahil"
+	"github.com/Nyeogmi/sahil-go/sahil/codec"
+)
+
+// these run CheckContract against every constructor and stage in sahil and
+// sahil/codec
+
+func ints(n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		out[i] = i
+	}
+	return out
+}
+
+func identity(x int) (int, error) { return x, nil }
+
+func TestContractConstructors(t *testing.T) {
+	constructors := map[string]func(sahil.Paginated[int]) sahil.Paginated[int]{
+		"Empty": func(sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.Empty[int]()
+		},
+		"Slice": func(sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.Slice(ints(30))
+		},
+		"Func": func(sahil.Paginated[int]) sahil.Paginated[int] {
+			i := 0
+			return sahil.Func(func() (int, error) {
+				if i == 30 {
+					return 0, sahil.EOF
+				}
+				i += 1
+				return i, nil
+			})
+		},
+		"SliceFunc": func(sahil.Paginated[int]) sahil.Paginated[int] {
+			i := 0
+			return sahil.SliceFunc(func() ([]int, error) {
+				if i == 10 {
+					return nil, sahil.EOF
+				}
+				i += 1
+				return ints(i), nil
+			})
+		},
+		"Cursor": func(sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.Cursor(0, func(page int) ([]int, int, error) {
+				if page == 8 {
+					return nil, 0, sahil.EOF
+				}
+				return ints(page), page + 1, nil
+			})
+		},
+		"Channel": func(sahil.Paginated[int]) sahil.Paginated[int] {
+			ch := make(chan int, 30)
+			for i := 0; i < 30; i++ {
+				ch <- i
+			}
+			close(ch)
+			return sahil.Channel(ch)
+		},
+		"Lines": func(sahil.Paginated[int]) sahil.Paginated[int] {
+			lines := sahil.Lines(strings.NewReader(strings.Repeat("Myotis\n", 30)))
+			return sahil.Map(lines, func(s string) (int, error) { return len(s), nil })
+		},
+		"Scanner": func(sahil.Paginated[int]) sahil.Paginated[int] {
+			scanner := bufio.NewScanner(strings.NewReader(strings.Repeat("Myotis lucifugus ", 15)))
+			scanner.Split(bufio.ScanWords)
+			return sahil.Map(sahil.Scanner(scanner), func(s string) (int, error) { return len(s), nil })
+		},
+		"WalkDir": func(sahil.Paginated[int]) sahil.Paginated[int] {
+			fsys := fstest.MapFS{}
+			for i := 0; i < 20; i++ {
+				fsys[fmt.Sprintf("roost%d/census%d.log", i%4, i)] = &fstest.MapFile{}
+			}
+			entries := sahil.WalkDir(fsys, ".", sahil.WalkOptions{Include: []string{"*.log"}})
+			return sahil.Map(entries, func(e sahil.WalkEntry) (int, error) { return len(e.Path), nil })
+		},
+	}
+
+	for name, factory := range constructors {
+		t.Run(name, func(t *testing.T) {
+			CheckContract(t, factory, ContractOptions{})
+		})
+	}
+}
+
+func TestContractStages(t *testing.T) {
+	stages := map[string]func(sahil.Paginated[int]) sahil.Paginated[int]{
+		"Map": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.Map(p, func(x int) (int, error) { return x * 2, nil })
+		},
+		"Filter": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.Filter(p, func(x int) (bool, error) { return x%3 == 0, nil })
+		},
+		"MapWindowed": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.MapWindowed(p, func(xs []int) ([]int, error) {
+				var out []int
+				for _, x := range xs {
+					for i := 0; i < x%3; i++ {
+						out = append(out, x)
+					}
+				}
+				return out, nil
+			})
+		},
+		"MapWindowedLimited": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			limiter := sahil.NewLimiter(context.Background(), 10, 100, NewClock(time.Unix(0, 0)))
+			return sahil.MapWindowedLimited(p, func(xs []int) ([]int, error) { return xs, nil }, limiter)
+		},
+		"RateLimit": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			limiter := sahil.NewLimiter(context.Background(), 10, 100, NewClock(time.Unix(0, 0)))
+			return sahil.RateLimit(p, limiter)
+		},
+		"MapWithState": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.MapWithState(p, 0, func(s, x int) (int, int, error) { return s + 1, s * x, nil })
+		},
+		"Scan": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.Scan(p, 0, func(s, x int) (int, error) { return s + x, nil })
+		},
+		"FlatMap": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.FlatMap(p, func(x int) (sahil.Paginated[int], error) {
+				return sahil.Slice(ints(x % 5)), nil
+			})
+		},
+		"Flatten": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.Flatten(sahil.Map(p, func(x int) (sahil.Paginated[int], error) {
+				return sahil.Slice([]int{x, x}), nil
+			}))
+		},
+		"Concat": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.Concat(sahil.Slice([]int{-2, -1}), p, sahil.Slice([]int{100}))
+		},
+		"Distinct": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.Distinct(sahil.Map(p, func(x int) (int, error) { return x % 7, nil }))
+		},
+		"DistinctByWindow": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.DistinctByWindow(p, func(x int) (int, error) { return x % 7, nil }, 3)
+		},
+		"DistinctByBloom": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.DistinctByBloom(p, func(x int) (string, error) { return fmt.Sprint(x % 7), nil }, 100, 0.01)
+		},
+		"GroupAdjacent": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			groups := sahil.GroupAdjacent(p, func(x int) (int, error) { return x / 3, nil })
+			return sahil.Map(groups, func(g sahil.Group[int, int]) (int, error) { return len(g.Elements), nil })
+		},
+		"ChunkBy": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			chunks := sahil.ChunkBy(p, func(a, b int) (bool, error) { return b%4 != 0, nil })
+			return sahil.Map(chunks, func(xs []int) (int, error) { return len(xs), nil })
+		},
+		"Chunk": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.Map(sahil.Chunk(p, 3), func(xs []int) (int, error) { return xs[0], nil })
+		},
+		"Window": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.Map(sahil.Window(p, 3, 2), func(xs []int) (int, error) { return xs[len(xs)-1], nil })
+		},
+		"Sort": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.Sort(p, func(a, b int) bool { return a%5 < b%5 || (a%5 == b%5 && a < b) }, 7)
+		},
+		"InnerJoin": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			joined := sahil.InnerJoin(p, sahil.Slice(ints(10)), identity, func(r int) (int, error) { return r * 2, nil }, 0)
+			return sahil.Map(joined, func(pair sahil.Pair[int, int]) (int, error) { return pair.Right, nil })
+		},
+		"LeftJoin": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			joined := sahil.LeftJoin(p, sahil.Slice(ints(10)), identity, identity, 0)
+			return sahil.Map(joined, func(pair sahil.Pair[int, *int]) (int, error) {
+				if pair.Right == nil {
+					return -1, nil
+				}
+				return *pair.Right, nil
+			})
+		},
+		"SemiJoin": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.SemiJoin(p, sahil.Slice(ints(10)), identity, identity, 0)
+		},
+		"AntiJoin": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.AntiJoin(p, sahil.Slice(ints(10)), identity, identity, 0)
+		},
+		"MergeJoin": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			joined := sahil.MergeJoin(p, sahil.Slice([]int{0, 0, 3, 4, 4, 9, 50}), compareInts)
+			return sahil.Map(joined, func(pair sahil.Pair[int, int]) (int, error) { return pair.Left, nil })
+		},
+		"MergeOuterJoin": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			joined := sahil.MergeOuterJoin(p, sahil.Slice([]int{0, 0, 3, 4, 4, 9, 50}), compareInts)
+			return sahil.Map(joined, func(pair sahil.Pair[*int, *int]) (int, error) {
+				if pair.Left == nil {
+					return -*pair.Right, nil
+				}
+				return *pair.Left, nil
+			})
+		},
+		"WithSpill": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.FlatMap(p, func(x int) (sahil.Paginated[int], error) {
+				return sahil.Slice(ints(x % 5)), nil
+			}).WithSpill(sahil.SpillPolicy{Threshold: 2, Dir: t.TempDir()})
+		},
+		"OnClose": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			return sahil.Map(p, identity).OnClose(func() error { return nil })
+		},
+	}
+
+	for name, factory := range stages {
+		t.Run(name, func(t *testing.T) {
+			CheckContract(t, factory, ContractOptions{})
+		})
+	}
+}
+
+func TestContractUnordered(t *testing.T) {
+	CheckContract(t, func(p sahil.Paginated[int]) sahil.Paginated[int] {
+		return sahil.Shuffle(p, 4, rand.New(rand.NewSource(1)))
+	}, ContractOptions{Unordered: true})
+}
+
+func compareInts(a, b int) int { return a - b }
+
+// failed stands in for a codec source when writing its input failed.
+func failed(err error) sahil.Paginated[int] {
+	return sahil.Func(func() (int, error) { return 0, err })
+}
+
+type csvRow struct {
+	N int `csv:"n"`
+}
+
+// The codec sources read what the matching sink wrote from upstream, so an
+// error from upstream comes out of the sink, and is passed on by failed.
+func TestContractCodecs(t *testing.T) {
+	codecs := map[string]func(sahil.Paginated[int]) sahil.Paginated[int]{
+		"JSONLines": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			var buf bytes.Buffer
+			if err := codec.WriteJSONLines(&buf, p, 7); err != nil {
+				return failed(err)
+			}
+			return codec.JSONLines[int](&buf)
+		},
+		"CSV": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			var buf bytes.Buffer
+			rows := sahil.Map(p, func(x int) (csvRow, error) { return csvRow{x}, nil })
+			if err := codec.WriteCSV(&buf, rows, 7, codec.CSVOptions{}); err != nil {
+				return failed(err)
+			}
+			return sahil.Map(codec.CSV[csvRow](&buf, codec.CSVOptions{}), func(r csvRow) (int, error) {
+				return r.N, nil
+			})
+		},
+		"CSVRecords": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			var buf bytes.Buffer
+			records := sahil.Map(p, func(x int) ([]string, error) { return []string{strconv.Itoa(x)}, nil })
+			if err := codec.WriteCSVRecords(&buf, records, 7, codec.CSVOptions{}); err != nil {
+				return failed(err)
+			}
+			return sahil.Map(codec.CSVRecords(&buf, codec.CSVOptions{}), func(r []string) (int, error) {
+				return strconv.Atoi(r[0])
+			})
+		},
+		"Gob": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			var buf bytes.Buffer
+			if err := codec.WriteGob(&buf, p, 7); err != nil {
+				return failed(err)
+			}
+			return codec.Gob[int](&buf)
+		},
+		"Framed": func(p sahil.Paginated[int]) sahil.Paginated[int] {
+			var buf bytes.Buffer
+			err := codec.WriteFramed(&buf, p, 7, func(x int) ([]byte, error) {
+				return []byte(strconv.Itoa(x)), nil
+			})
+			if err != nil {
+				return failed(err)
+			}
+			return codec.Framed(&buf, func(b []byte) (int, error) { return strconv.Atoi(string(b)) })
+		},
+	}
+
+	for name, factory := range codecs {
+		t.Run(name, func(t *testing.T) {
+			CheckContract(t, factory, ContractOptions{})
+		})
+	}
+}
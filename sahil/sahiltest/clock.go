@@ -0,0 +1,53 @@
+package sahiltest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a fake sahil.Clock. Time only moves when something waits on it or
+// calls Advance, so tests involving time are fast and deterministic.
+type Clock struct {
+	mutex sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+// NewClock creates a Clock set to start. To test deadlines, pass the Clock to
+// Paginated.FetchWithinClock, so the deadline is measured by it rather than by
+// the system clock.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+func (c *Clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// After moves the clock forward by d, then returns a channel that has already
+// fired. The wait is recorded for Waits.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.waits = append(c.waits, d)
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Waits returns the duration of every call to After so far.
+func (c *Clock) Waits() []time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]time.Duration{}, c.waits...)
+}
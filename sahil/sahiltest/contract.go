@@ -0,0 +1,319 @@
+package sahiltest
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Nyeogmi/sahil-go/sahil"
+)
+
+// ErrInjected is the error CheckContract injects into upstream Sources.
+var ErrInjected = errors.New("injected upstream error")
+
+// ContractOptions configures CheckContract. The zero value is ready to use.
+type ContractOptions struct {
+	// Trials is the number of random fetch sequences to try for each kind of
+	// check. The default is 50.
+	Trials int
+
+	// MaxLen is the longest upstream to build the stage on. The default is 40.
+	MaxLen int
+
+	// Seed seeds the random choices, so a failure can be reproduced.
+	Seed int64
+
+	// Unordered allows the order of the output to depend on the batch sizes
+	// asked for, as it does for Shuffle. The output is still compared to the
+	// expected output as a multiset.
+	Unordered bool
+}
+
+// CheckContract property-tests a Paginated stage against the contract every
+// Paginated keeps:
+//
+//   - Fetch(n) produces at least n elements unless the stage is exhausted, and
+//     at most 2n. FetchRange(n, m) produces at most m.
+//   - Once a fetch comes up short, or FetchBatch reports exhaustion, every
+//     later fetch produces nil.
+//   - The elements produced don't depend on the batch sizes asked for.
+//   - An error from upstream is passed on, with nothing alongside it, and
+//     reproduced on every later fetch.
+//   - Closing the stage closes its upstream exactly once, and later fetches
+//     produce nil.
+//   - The stage never fetches from its upstream after the upstream is
+//     exhausted.
+//
+// factory builds the stage on top of upstream, a Paginated of 0, 1, 2, ... of
+// random length. It is called afresh for each trial, so it must build
+// everything it uses (for example a seeded *rand.Rand) from scratch. A stage
+// that doesn't read from upstream, such as one built with sahil.Slice, is
+// checked all the same, minus the checks involving upstream.
+//
+// The expected output for each upstream is taken from a run that fetches
+// everything in large batches. CheckContract reports the first violation it
+// finds, along with the fetches that led to it, and returns false.
+func CheckContract[T any](t testing.TB, factory func(upstream sahil.Paginated[int]) sahil.Paginated[T], opts ContractOptions) bool {
+	t.Helper()
+
+	if opts.Trials < 1 {
+		opts.Trials = 50
+	}
+	if opts.MaxLen < 1 {
+		opts.MaxLen = 40
+	}
+
+	c := contract[T]{factory: factory, opts: opts, rng: rand.New(rand.NewSource(opts.Seed))}
+	for trial := 0; trial < opts.Trials; trial++ {
+		for _, check := range []func() error{c.checkFetches, c.checkClose, c.checkErr} {
+			if err := check(); err != nil {
+				t.Errorf("contract violated (seed %d, trial %d): %v\nfetches:\n%s", opts.Seed, trial, err, c.log.String())
+				return false
+			}
+		}
+	}
+	return true
+}
+
+type contract[T any] struct {
+	factory func(sahil.Paginated[int]) sahil.Paginated[T]
+	opts    ContractOptions
+	rng     *rand.Rand
+	log     strings.Builder
+}
+
+func (c *contract[T]) upstream() []int {
+	input := make([]int, c.rng.Intn(c.opts.MaxLen+1))
+	for i := range input {
+		input[i] = i
+	}
+	return input
+}
+
+// expected fetches everything from a fresh stage in large batches.
+func (c *contract[T]) expected(input []int) ([]T, error) {
+	p := c.factory(sahil.FromSource(Elements(input...)))
+	var out []T
+	for {
+		result := p.FetchBatch(1024)
+		if result.Err != nil {
+			return nil, fmt.Errorf("fetching everything at once: %w", result.Err)
+		}
+		out = append(out, result.Items...)
+		if result.Exhausted {
+			return out, nil
+		}
+	}
+}
+
+// fetch makes one random fetch, checking its bounds. It reports whether the
+// stage is now exhausted.
+func (c *contract[T]) fetch(p sahil.Paginated[T]) ([]T, bool, error) {
+	atLeast := 1 + c.rng.Intn(8)
+
+	var results []T
+	var err error
+	exhausted := false
+	atMost := 2 * atLeast
+	switch c.rng.Intn(3) {
+	case 0:
+		results, err = p.Fetch(atLeast)
+		fmt.Fprintf(&c.log, "  Fetch(%d): %d elements, %v\n", atLeast, len(results), err)
+	case 1:
+		atMost = atLeast + c.rng.Intn(4)
+		results, err = p.FetchRange(atLeast, atMost)
+		fmt.Fprintf(&c.log, "  FetchRange(%d, %d): %d elements, %v\n", atLeast, atMost, len(results), err)
+	case 2:
+		result := p.FetchBatch(atLeast)
+		results, err, exhausted = result.Items, result.Err, result.Exhausted
+		fmt.Fprintf(&c.log, "  FetchBatch(%d): %d elements, exhausted %v, %v\n", atLeast, len(results), exhausted, err)
+	}
+
+	if err != nil {
+		if len(results) > 0 {
+			return nil, true, fmt.Errorf("%d elements produced alongside error %v", len(results), err)
+		}
+		return nil, true, err
+	}
+	if len(results) > atMost {
+		return nil, true, fmt.Errorf("%d elements produced, but at most %d were allowed", len(results), atMost)
+	}
+	if len(results) < atLeast {
+		exhausted = true
+	}
+	if exhausted != p.Exhausted() {
+		return nil, true, fmt.Errorf("fetch implied exhausted = %v, but Exhausted() = %v", exhausted, p.Exhausted())
+	}
+	return results, exhausted, nil
+}
+
+// checkDone checks that an exhausted stage only produces nil and err.
+func (c *contract[T]) checkDone(p sahil.Paginated[T], err error) error {
+	for i := 0; i < 2; i++ {
+		results, err2 := p.Fetch(1 + c.rng.Intn(8))
+		if len(results) > 0 {
+			return fmt.Errorf("%d elements produced after exhaustion", len(results))
+		}
+		if err2 != err {
+			return fmt.Errorf("error after exhaustion was %v, not %v", err2, err)
+		}
+	}
+	return nil
+}
+
+func (c *contract[T]) checkFetches() error {
+	c.log.Reset()
+	input := c.upstream()
+	expected, err := c.expected(input)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(&c.log, "  (upstream of %d elements)\n", len(input))
+	rec := Record(Elements(input...))
+	p := c.factory(rec.Paginated())
+
+	var actual []T
+	for {
+		results, exhausted, err := c.fetch(p)
+		if err != nil {
+			return err
+		}
+		actual = append(actual, results...)
+		if exhausted {
+			break
+		}
+	}
+
+	// checked before checkDone, in case the stage is upstream itself
+	if err := checkLate(rec); err != nil {
+		return err
+	}
+	if err := c.checkDone(p, nil); err != nil {
+		return err
+	}
+	if !c.same(expected, actual) {
+		return fmt.Errorf("produced %v, but fetching everything at once produced %v", actual, expected)
+	}
+	if rec.Closes() > 1 {
+		return fmt.Errorf("upstream closed %d times", rec.Closes())
+	}
+	return nil
+}
+
+func (c *contract[T]) checkClose() error {
+	c.log.Reset()
+	input := c.upstream()
+	fmt.Fprintf(&c.log, "  (upstream of %d elements)\n", len(input))
+	rec := Record(Elements(input...))
+	p := c.factory(rec.Paginated())
+
+	for n := c.rng.Intn(3); n > 0; n-- {
+		_, exhausted, err := c.fetch(p)
+		if err != nil {
+			return err
+		}
+		if exhausted {
+			break
+		}
+	}
+
+	if err := checkLate(rec); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(&c.log, "  Close()\n")
+	p.Close()
+	if err := c.checkDone(p, nil); err != nil {
+		return err
+	}
+	if len(rec.Calls()) > 0 && rec.Closes() != 1 {
+		return fmt.Errorf("upstream closed %d times after Close", rec.Closes())
+	}
+	return nil
+}
+
+func (c *contract[T]) checkErr() error {
+	c.log.Reset()
+	input := c.upstream()
+	expected, err := c.expected(input)
+	if err != nil {
+		return err
+	}
+
+	n := c.rng.Intn(len(input) + 1)
+	fmt.Fprintf(&c.log, "  (upstream of %d elements, failing after %d)\n", len(input), n)
+	rec := Record(ErrorAfter(Elements(input...), n, ErrInjected))
+	p := c.factory(rec.Paginated())
+
+	var actual []T
+	for {
+		results, exhausted, err := c.fetch(p)
+		if err != nil {
+			if !errors.Is(err, ErrInjected) {
+				return err
+			}
+			if err := checkLate(rec); err != nil {
+				return err
+			}
+			if err := c.checkDone(p, err); err != nil {
+				return err
+			}
+			break
+		}
+		actual = append(actual, results...)
+		if exhausted {
+			if err := checkLate(rec); err != nil {
+				return err
+			}
+			for _, call := range rec.Calls() {
+				if call.Err != nil {
+					return errors.New("upstream produced an error, but the stage didn't pass it on")
+				}
+			}
+			break
+		}
+	}
+
+	if c.opts.Unordered || len(actual) == 0 {
+		return nil
+	}
+	if len(actual) > len(expected) || !reflect.DeepEqual(actual, expected[:len(actual)]) {
+		return fmt.Errorf("produced %v before the error, which doesn't start %v", actual, expected)
+	}
+	return nil
+}
+
+// checkLate checks that the stage left upstream alone once it was exhausted.
+func checkLate(rec *Recorder[int]) error {
+	if late := rec.LateFetches(); len(late) > 0 {
+		return fmt.Errorf("fetched from upstream %d more times after it was exhausted", len(late))
+	}
+	return nil
+}
+
+// same compares outputs, ignoring order if the options allow it.
+func (c *contract[T]) same(expected, actual []T) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	if !c.opts.Unordered {
+		return len(expected) == 0 || reflect.DeepEqual(expected, actual)
+	}
+
+	used := make([]bool, len(actual))
+outer:
+	for _, e := range expected {
+		for i, a := range actual {
+			if !used[i] && reflect.DeepEqual(e, a) {
+				used[i] = true
+				continue outer
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package sahiltest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Nyeogmi/sahil-go/sahil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckContract(t *testing.T) {
+	ft := &fakeT{}
+	assert.True(t, CheckContract(ft, func(p sahil.Paginated[int]) sahil.Paginated[int] {
+		return p
+	}, ContractOptions{}))
+	assert.Empty(t, ft.errors)
+}
+
+func TestCheckContractSwallowedErr(t *testing.T) {
+	ft := &fakeT{}
+	assert.False(t, CheckContract(ft, func(p sahil.Paginated[int]) sahil.Paginated[int] {
+		return sahil.Func(func() (int, error) {
+			xs, err := p.FetchRange(1, 1)
+			if err != nil || len(xs) == 0 {
+				return 0, sahil.EOF
+			}
+			return xs[0], nil
+		}).OnClose(p.Close)
+	}, ContractOptions{}))
+	assert.Len(t, ft.errors, 1)
+	assert.Contains(t, ft.errors[0], "didn't pass it on")
+}
+
+func TestCheckContractBatchDependent(t *testing.T) {
+	// reverses each batch, so the output depends on the batch sizes
+	ft := &fakeT{}
+	assert.False(t, CheckContract(ft, func(p sahil.Paginated[int]) sahil.Paginated[int] {
+		return sahil.MapWindowed(p, func(xs []int) ([]int, error) {
+			out := make([]int, len(xs))
+			for i, x := range xs {
+				out[len(xs)-1-i] = x
+			}
+			return out, nil
+		})
+	}, ContractOptions{}))
+	assert.Len(t, ft.errors, 1)
+
+	// unless the order is allowed to change
+	ft = &fakeT{}
+	assert.True(t, CheckContract(ft, func(p sahil.Paginated[int]) sahil.Paginated[int] {
+		return sahil.MapWindowed(p, func(xs []int) ([]int, error) {
+			out := make([]int, len(xs))
+			for i, x := range xs {
+				out[len(xs)-1-i] = x
+			}
+			return out, nil
+		})
+	}, ContractOptions{Unordered: true}))
+	assert.Empty(t, ft.errors)
+}
+
+func TestCheckContractWrongErr(t *testing.T) {
+	ft := &fakeT{}
+	assert.False(t, CheckContract(ft, func(p sahil.Paginated[int]) sahil.Paginated[int] {
+		return sahil.Map(p, func(x int) (int, error) {
+			if x == 3 {
+				return 0, errors.New("MAP ERROR")
+			}
+			return x, nil
+		})
+	}, ContractOptions{}))
+	assert.Len(t, ft.errors, 1)
+	assert.Contains(t, ft.errors[0], "MAP ERROR")
+}
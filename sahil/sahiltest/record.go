@@ -0,0 +1,143 @@
+package sahiltest
+
+import (
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/Nyeogmi/sahil-go/sahil"
+)
+
+// Call is one call to a Recorder's Fetch.
+type Call[T any] struct {
+	AtLeast int
+	Batch   []T
+	Err     error
+}
+
+// Recorder wraps a Source, logging every call made to it.
+//
+// It also logs late fetches: calls to Fetch after it has signaled that it is
+// done, and fetches from its Paginated after that Paginated is exhausted. The
+// second kind never reach Fetch, since a Paginated answers them itself, but
+// sahil tells the Recorder about them. Either way, whatever is fetching is
+// doing pointless work.
+type Recorder[T any] struct {
+	underlying  sahil.Source[T]
+	mutex       sync.Mutex
+	calls       []Call[T]
+	done        bool // a call has returned an error or a short batch
+	lateFetches []int
+	closes      int
+}
+
+// Record wraps src in a Recorder.
+func Record[T any](src sahil.Source[T]) *Recorder[T] {
+	return &Recorder[T]{underlying: src}
+}
+
+// Paginated turns the Recorder into a Paginated, so that everything the
+// Paginated does to its Source is logged, along with any fetches from the
+// Paginated after it is exhausted.
+func (r *Recorder[T]) Paginated() sahil.Paginated[T] {
+	return sahil.FromSource[T](r)
+}
+
+func (r *Recorder[T]) Fetch(atLeast int) ([]T, error) {
+	r.mutex.Lock()
+	if r.done {
+		r.lateFetches = append(r.lateFetches, atLeast)
+	}
+	r.mutex.Unlock()
+
+	batch, err := r.underlying.Fetch(atLeast)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.calls = append(r.calls, Call[T]{AtLeast: atLeast, Batch: batch, Err: err})
+	if err != nil || len(batch) < atLeast {
+		r.done = true
+	}
+	return batch, err
+}
+
+// LateFetch logs a fetch from the Recorder's Paginated after it was
+// exhausted. sahil calls it: tests shouldn't need to.
+func (r *Recorder[T]) LateFetch(atLeast int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.lateFetches = append(r.lateFetches, atLeast)
+}
+
+// Close closes the wrapped Source, if it is an io.Closer.
+func (r *Recorder[T]) Close() error {
+	r.mutex.Lock()
+	r.closes += 1
+	r.mutex.Unlock()
+
+	if closer, ok := r.underlying.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Calls returns every call to Fetch so far.
+func (r *Recorder[T]) Calls() []Call[T] {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]Call[T]{}, r.calls...)
+}
+
+// LateFetches returns the atLeast of every late fetch so far: every call to
+// Fetch after the Recorder signaled that it was done, and every fetch from its
+// Paginated after the Paginated was exhausted.
+func (r *Recorder[T]) LateFetches() []int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]int{}, r.lateFetches...)
+}
+
+// Closes returns the number of times Close has been called.
+func (r *Recorder[T]) Closes() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.closes
+}
+
+// AssertFetchCalls checks that Fetch has been called exactly once for each of
+// atLeasts, in order, with those values of atLeast.
+func AssertFetchCalls[T any](t testing.TB, r *Recorder[T], atLeasts ...int) bool {
+	t.Helper()
+
+	calls := r.Calls()
+	actual := make([]int, len(calls))
+	for i, call := range calls {
+		actual[i] = call.AtLeast
+	}
+
+	if len(actual) != len(atLeasts) {
+		t.Errorf("expected Fetch calls with atLeast %v, got %v", atLeasts, actual)
+		return false
+	}
+	for i := range actual {
+		if actual[i] != atLeasts[i] {
+			t.Errorf("expected Fetch calls with atLeast %v, got %v", atLeasts, actual)
+			return false
+		}
+	}
+	return true
+}
+
+// AssertNoCallsAfterExhaustion checks that there were no late fetches: Fetch
+// was never called again after it signaled that the Source was done, by
+// returning an error or fewer elements than asked for, and nothing fetched
+// from the Recorder's Paginated after it was exhausted.
+func AssertNoCallsAfterExhaustion[T any](t testing.TB, r *Recorder[T]) bool {
+	t.Helper()
+
+	if late := r.LateFetches(); len(late) > 0 {
+		t.Errorf("fetched %d times after it was exhausted", len(late))
+		return false
+	}
+	return true
+}
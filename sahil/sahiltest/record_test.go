@@ -0,0 +1,95 @@
+package sahiltest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Nyeogmi/sahil-go/sahil"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeT collects failures instead of failing the test.
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRecord(t *testing.T) {
+	rec := Record(ErrorAfter(Elements(1, 2, 3), 2, errors.New("INJECTED ERROR")))
+	src := sahil.Map(rec.Paginated(), func(x int) (int, error) {
+		return x * 10, nil
+	})
+
+	_, err := src.Fetch(2)
+	assert.Nil(t, err)
+	_, err = src.Fetch(1)
+	assert.EqualError(t, err, "INJECTED ERROR")
+	_, err = src.Fetch(1)
+	assert.EqualError(t, err, "INJECTED ERROR")
+
+	calls := rec.Calls()
+	assert.Len(t, calls, 2)
+	assert.EqualValues(t, Call[int]{AtLeast: 2, Batch: []int{1, 2}}, calls[0])
+	assert.EqualError(t, calls[1].Err, "INJECTED ERROR")
+	assert.Equal(t, 1, rec.Closes())
+
+	AssertNoCallsAfterExhaustion(t, rec)
+}
+
+func TestAssertFetchCalls(t *testing.T) {
+	rec := Record(Elements(1, 2, 3))
+	_, err := rec.Paginated().Fetch(2)
+	assert.Nil(t, err)
+
+	ft := &fakeT{}
+	assert.True(t, AssertFetchCalls(ft, rec, 2))
+	assert.False(t, AssertFetchCalls(ft, rec, 3))
+	assert.False(t, AssertFetchCalls(ft, rec, 2, 1))
+	assert.Len(t, ft.errors, 2)
+}
+
+func TestAssertNoCallsAfterExhaustion(t *testing.T) {
+	// a stage that checks one last time before giving up
+	rec := Record(Elements(1, 2, 3))
+	upstream := rec.Paginated()
+	src := sahil.Func(func() (int, error) {
+		xs, err := upstream.FetchRange(1, 1)
+		if err != nil {
+			return 0, err
+		}
+		if len(xs) == 0 {
+			upstream.Fetch(1)
+			return 0, sahil.EOF
+		}
+		return xs[0], nil
+	})
+
+	results, err := src.Fetch(5)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 2, 3}, results)
+	assert.EqualValues(t, []int{1}, rec.LateFetches())
+
+	ft := &fakeT{}
+	assert.False(t, AssertNoCallsAfterExhaustion(ft, rec))
+	assert.EqualValues(t, []string{"fetched 1 times after it was exhausted"}, ft.errors)
+}
+
+func TestAssertNoCallsAfterExhaustionSource(t *testing.T) {
+	// calling a Source directly skips the protection Paginated provides
+	rec := Record(Elements(1, 2, 3))
+	rec.Fetch(5)
+	rec.Fetch(1)
+	rec.Fetch(2)
+	assert.EqualValues(t, []int{1, 2}, rec.LateFetches())
+
+	ft := &fakeT{}
+	assert.False(t, AssertNoCallsAfterExhaustion(ft, rec))
+	assert.EqualValues(t, []string{"fetched 2 times after it was exhausted"}, ft.errors)
+}
@@ -0,0 +1,148 @@
+// Package sahiltest provides Sources and assertions for testing code built on
+// sahil.
+//
+// The Sources here are meant to be wrapped with Record, which logs every call
+// made to them, and then turned into a Paginated with Recorder.Paginated,
+// which also logs fetches that arrive after it is exhausted.
+package sahiltest
+
+import (
+	"time"
+
+	"github.com/Nyeogmi/sahil-go/sahil"
+)
+
+type elements[T any] struct {
+	ts []T
+}
+
+// Elements creates a Source producing ts, exactly atLeast at a time.
+func Elements[T any](ts ...T) sahil.Source[T] {
+	return &elements[T]{ts}
+}
+
+func (e *elements[T]) Fetch(atLeast int) ([]T, error) {
+	n := atLeast
+	if n > len(e.ts) {
+		n = len(e.ts)
+	}
+	out := append([]T{}, e.ts[:n]...)
+	e.ts = e.ts[n:]
+	return out, nil
+}
+
+type scripted[T any] struct {
+	ts    []T
+	sizes []int
+}
+
+// Scripted creates a Source producing ts in batches of the given sizes: the
+// first call produces sizes[0] elements, the second sizes[1], and so on, with
+// the last size repeating. A call is never allowed to produce fewer than
+// atLeast elements before ts runs out, so a size smaller than that is
+// rounded up.
+//
+// This is useful for checking how a stage copes with an upstream that
+// produces more than it was asked for.
+func Scripted[T any](ts []T, sizes ...int) sahil.Source[T] {
+	return &scripted[T]{ts: ts, sizes: sizes}
+}
+
+func (s *scripted[T]) Fetch(atLeast int) ([]T, error) {
+	n := atLeast
+	if len(s.sizes) > 0 {
+		if s.sizes[0] > n {
+			n = s.sizes[0]
+		}
+		if len(s.sizes) > 1 {
+			s.sizes = s.sizes[1:]
+		}
+	}
+	if n > len(s.ts) {
+		n = len(s.ts)
+	}
+	out := append([]T{}, s.ts[:n]...)
+	s.ts = s.ts[n:]
+	return out, nil
+}
+
+type errorAfter[T any] struct {
+	underlying sahil.Source[T]
+	n          int
+	err        error
+}
+
+// ErrorAfter creates a Source producing the first n elements of src, then
+// err. If src runs out first, so does the Source, without an error.
+//
+// A call that would go past the nth element fails. Since a Paginated discards
+// the results that come with an error, the first n elements are only seen by
+// callers that stop asking for more before then.
+func ErrorAfter[T any](src sahil.Source[T], n int, err error) sahil.Source[T] {
+	return &errorAfter[T]{underlying: src, n: n, err: err}
+}
+
+func (e *errorAfter[T]) Fetch(atLeast int) ([]T, error) {
+	out, err := e.underlying.Fetch(atLeast)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) < atLeast && len(out) <= e.n {
+		// src ran out before reaching the error
+		return out, nil
+	}
+	if atLeast > e.n {
+		return nil, e.err
+	}
+	if len(out) > e.n {
+		out = out[:e.n]
+	}
+	e.n -= len(out)
+	return out, nil
+}
+
+type slow[T any] struct {
+	underlying sahil.Source[T]
+	clock      *Clock
+	perCall    time.Duration
+	perElement time.Duration
+}
+
+// Slow wraps src so that each call takes perCall, plus perElement for each
+// element produced, as measured by clock. Nothing actually sleeps: the clock
+// is moved forward instead.
+//
+// Slow implements sahil.DeadlineSource, stopping early once clock passes the
+// deadline. Use Paginated.FetchWithinClock with the same clock, so that the
+// stages downstream of Slow measure the deadline the same way.
+func Slow[T any](src sahil.Source[T], clock *Clock, perCall, perElement time.Duration) sahil.Source[T] {
+	return &slow[T]{underlying: src, clock: clock, perCall: perCall, perElement: perElement}
+}
+
+func (s *slow[T]) Fetch(atLeast int) ([]T, error) {
+	out, _, err := s.FetchWithin(atLeast, time.Time{})
+	return out, err
+}
+
+func (s *slow[T]) FetchWithin(atLeast int, deadline time.Time) ([]T, bool, error) {
+	s.clock.Advance(s.perCall)
+
+	// fetch one element at a time, so that time passes between them
+	var out []T
+	for len(out) < atLeast {
+		if !deadline.IsZero() && !s.clock.Now().Before(deadline) {
+			return out, true, nil
+		}
+
+		batch, err := s.underlying.Fetch(1)
+		if err != nil {
+			return nil, false, err
+		}
+		s.clock.Advance(s.perElement * time.Duration(len(batch)))
+		out = append(out, batch...)
+		if len(batch) < 1 {
+			break
+		}
+	}
+	return out, false, nil
+}
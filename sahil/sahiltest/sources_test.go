@@ -0,0 +1,109 @@
+package sahiltest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nyeogmi/sahil-go/sahil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestElements(t *testing.T) {
+	rec := Record(Elements(1, 2, 3, 4, 5))
+	src := rec.Paginated()
+
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 2}, results)
+
+	results, err = src.Fetch(4)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{3, 4, 5}, results)
+
+	AssertFetchCalls(t, rec, 2, 4)
+	assert.Equal(t, 1, rec.Closes())
+}
+
+func TestScripted(t *testing.T) {
+	src := Scripted([]int{1, 2, 3, 4, 5, 6, 7, 8}, 4, 1)
+
+	results, err := src.Fetch(1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 2, 3, 4}, results)
+
+	// the last size repeats, but never goes below atLeast
+	results, err = src.Fetch(1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{5}, results)
+	results, err = src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{6, 7}, results)
+
+	results, err = src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{8}, results)
+}
+
+func TestScriptedBuffering(t *testing.T) {
+	rec := Record(Scripted([]int{1, 2, 3, 4, 5, 6}, 4, 1))
+	src := rec.Paginated()
+
+	// the first call overshoots, and the rest is buffered
+	results, err := src.FetchRange(1, 1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1}, results)
+	assert.Len(t, rec.Calls(), 1)
+
+	results, err = src.Fetch(10)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{2, 3, 4, 5, 6}, results)
+	AssertNoCallsAfterExhaustion(t, rec)
+}
+
+func TestErrorAfter(t *testing.T) {
+	src := sahil.FromSource(ErrorAfter(Elements(1, 2, 3, 4, 5), 3, errors.New("INJECTED ERROR")))
+
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 2}, results)
+
+	results, err = src.Fetch(2)
+	assert.Equal(t, 0, len(results))
+	assert.EqualError(t, err, "INJECTED ERROR")
+
+	// running out first means no error
+	src = sahil.FromSource(ErrorAfter(Elements(1, 2), 3, errors.New("INJECTED ERROR")))
+	results, err = src.Fetch(5)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 2}, results)
+}
+
+func TestSlow(t *testing.T) {
+	clock := NewClock(time.Unix(0, 0))
+	start := clock.Now()
+	src := sahil.FromSource(Slow(Elements(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), clock, 5*time.Millisecond, 10*time.Millisecond))
+
+	results, err := src.Fetch(3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 2, 3}, results)
+	assert.Equal(t, 35*time.Millisecond, clock.Now().Sub(start))
+
+	results, timedOut, err := src.FetchWithinClock(5, clock.Now().Add(25*time.Millisecond), clock)
+	assert.Nil(t, err)
+	assert.True(t, timedOut)
+	assert.EqualValues(t, []int{4, 5}, results)
+}
+
+func TestClockLimiter(t *testing.T) {
+	clock := NewClock(time.Unix(0, 0))
+	limiter := sahil.NewLimiter(context.Background(), 1, 0, clock)
+
+	src := sahil.RateLimit(sahil.FromSource(Elements(1, 2, 3)), limiter)
+	for i := 0; i < 3; i++ {
+		_, err := src.Fetch(1)
+		assert.Nil(t, err)
+	}
+	assert.EqualValues(t, []time.Duration{time.Second, time.Second}, clock.Waits())
+}
@@ -0,0 +1,87 @@
+package sahil
+
+type mapWithState[S any, A any, B any] struct {
+	underlying Paginated[A]
+	state      S
+	fn         func(S, A) (S, B, error)
+}
+
+// MapWithState applies a function to the elements in a Paginated, threading
+// a piece of state from each call to the next.
+//
+// fn receives the state left by the previous element (or init, for the first
+// element) and returns the new state along with its output element. Elements
+// are visited in order, so the state behaves as if the whole stream had been
+// processed in a single loop.
+//
+// Like Map, this results in a new Paginated with the same number of elements.
+func MapWithState[S any, A any, B any](
+	p Paginated[A],
+	init S,
+	fn func(S, A) (S, B, error),
+) Paginated[B] {
+	return wrap[B](&mapWithState[S, A, B]{underlying: p, state: init, fn: fn})
+}
+
+// Scan produces a running aggregation over a Paginated.
+//
+// Each output element is the accumulator after folding in the corresponding
+// input element. For instance, Scan(Slice([]int{1, 2, 3}), 0, add) is
+// equivalent to Slice([]int{1, 3, 6}).
+func Scan[S any, A any](
+	p Paginated[A],
+	init S,
+	fn func(S, A) (S, error),
+) Paginated[S] {
+	return MapWithState(p, init, func(s S, a A) (S, S, error) {
+		s, err := fn(s, a)
+		return s, s, err
+	})
+}
+
+func (m *mapWithState[S, A, B]) Fetch(atLeast int) ([]B, error) {
+	outA, err := m.underlying.Fetch(atLeast)
+	if err != nil {
+		return nil, err
+	}
+
+	// work on a copy so a failed batch doesn't leave the state half-updated
+	state := m.state
+	outB := make([]B, len(outA))
+	for i, a := range outA {
+		var b B
+		state, b, err = m.fn(state, a)
+		if err != nil {
+			return nil, err
+		}
+		outB[i] = b
+	}
+	m.state = state
+	return outB, nil
+}
+
+func (m *mapWithState[S, A, B]) Close() error {
+	return m.underlying.Close()
+}
+
+type mapWithStateCheckpoint[S any] struct {
+	State      S
+	Underlying []byte
+}
+
+func (m *mapWithState[S, A, B]) Checkpoint() ([]byte, error) {
+	underlying, err := m.underlying.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	return encodeCheckpoint(mapWithStateCheckpoint[S]{State: m.state, Underlying: underlying})
+}
+
+func (m *mapWithState[S, A, B]) Restore(checkpoint []byte) error {
+	var saved mapWithStateCheckpoint[S]
+	if err := decodeCheckpoint(checkpoint, &saved); err != nil {
+		return err
+	}
+	m.state = saved.State
+	return m.underlying.restore(saved.Underlying)
+}
@@ -0,0 +1,70 @@
+package sahil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScan(t *testing.T) {
+	src := Scan(
+		Slice([]int{1, 2, 3, 4, 5}),
+		0,
+		func(total int, x int) (int, error) {
+			return total + x, nil
+		},
+	)
+
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 3}, results)
+
+	results, err = src.Fetch(4)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{6, 10, 15}, results)
+}
+
+func TestMapWithState(t *testing.T) {
+	// assign each page of rows its starting offset
+	src := MapWithState(
+		Slice([][]string{
+			{"Desmodus", "Diaemus"},
+			{"Diphylla"},
+			{"Pteropus", "Rousettus", "Eidolon"},
+		}),
+		0,
+		func(offset int, page []string) (int, int, error) {
+			return offset + len(page), offset, nil
+		},
+	)
+
+	results, err := src.Fetch(3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{0, 2, 3}, results)
+}
+
+func TestScanErr(t *testing.T) {
+	src := Scan(
+		Slice([]int{1, 2, 3, 4, 5}),
+		0,
+		func(total int, x int) (int, error) {
+			if total+x > 5 {
+				return 0, errors.New("OVERDRAWN")
+			}
+			return total + x, nil
+		},
+	)
+
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 3}, results)
+
+	results, err = src.Fetch(1)
+	assert.Equal(t, 0, len(results))
+	assert.EqualError(t, err, "OVERDRAWN")
+
+	results, err = src.Fetch(1)
+	assert.Equal(t, 0, len(results))
+	assert.EqualError(t, err, "OVERDRAWN")
+}
@@ -0,0 +1,78 @@
+package sahil
+
+import (
+	"math/rand"
+)
+
+type shuffle[T any] struct {
+	underlying Paginated[T]
+	reservoir  []T
+	bufferSize int
+	rng        *rand.Rand
+}
+
+// Shuffle reorders the elements of a Paginated using a rotating buffer.
+//
+// Shuffle keeps a reservoir of up to bufferSize elements. Each output element
+// is picked at random from the reservoir, which is refilled from the
+// underlying Paginated in batches. An element can therefore move at most
+// about bufferSize positions earlier than where it started, but it can stay in
+// the reservoir for any number of picks, so there is no limit on how much
+// later it comes out. This is a local shuffle, not a uniform permutation of
+// the whole input.
+//
+// Once the underlying Paginated runs out, the remaining reservoir is emitted
+// in random order.
+//
+// Pass a seeded *rand.Rand to get reproducible output. A nil rng uses a
+// source seeded from the current time.
+func Shuffle[T any](p Paginated[T], bufferSize int, rng *rand.Rand) Paginated[T] {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	if rng == nil {
+		rng = rand.New(rand.NewSource(rand.Int63()))
+	}
+	return wrap[T](&shuffle[T]{
+		underlying: p,
+		bufferSize: bufferSize,
+		rng:        rng,
+	})
+}
+
+func (s *shuffle[T]) Fetch(atLeast int) ([]T, error) {
+	// keep bufferSize elements around after producing our output, so that
+	// every pick has the full reservoir to choose from
+	wanted := s.bufferSize + atLeast
+	if len(s.reservoir) < wanted && !s.underlying.Exhausted() {
+		input, err := s.underlying.Fetch(wanted - len(s.reservoir))
+		if err != nil {
+			return nil, err
+		}
+		s.reservoir = append(s.reservoir, input...)
+	}
+
+	n := atLeast
+	if s.underlying.Exhausted() || n > len(s.reservoir) {
+		// nothing more is coming, so drain what we have
+		n = len(s.reservoir)
+	}
+
+	out := make([]T, n)
+	for i := range out {
+		last := len(s.reservoir) - 1
+		j := s.rng.Intn(last + 1)
+		out[i] = s.reservoir[j]
+		s.reservoir[j] = s.reservoir[last]
+
+		var zero T
+		s.reservoir[last] = zero // allow this element to be freed
+		s.reservoir = s.reservoir[:last]
+	}
+	return out, nil
+}
+
+func (s *shuffle[T]) Close() error {
+	s.reservoir = nil
+	return s.underlying.Close()
+}
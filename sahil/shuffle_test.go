@@ -0,0 +1,88 @@
+package sahil
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShuffle(t *testing.T) {
+	var input []int
+	for i := 0; i < 100; i++ {
+		input = append(input, i)
+	}
+
+	src := Shuffle(Slice(input), 10, rand.New(rand.NewSource(42)))
+
+	var results []int
+	for {
+		batch, err := src.Fetch(7)
+		assert.Nil(t, err)
+		results = append(results, batch...)
+		if len(batch) < 7 {
+			break
+		}
+	}
+
+	assert.NotEqual(t, input, results)
+	sort.Ints(results)
+	assert.EqualValues(t, input, results)
+}
+
+func TestShuffleReproducible(t *testing.T) {
+	input := []string{"Pteropus", "Rousettus", "Eidolon", "Epomops", "Nyctimene"}
+
+	src1 := Shuffle(Slice(input), 3, rand.New(rand.NewSource(7)))
+	src2 := Shuffle(Slice(input), 3, rand.New(rand.NewSource(7)))
+
+	results1, err := src1.Fetch(5)
+	assert.Nil(t, err)
+	results2, err := src2.Fetch(5)
+	assert.Nil(t, err)
+
+	assert.EqualValues(t, results1, results2)
+	assert.ElementsMatch(t, input, results1)
+}
+
+func TestShuffleLocality(t *testing.T) {
+	var input []int
+	for i := 0; i < 50; i++ {
+		input = append(input, i)
+	}
+
+	// nothing can be emitted before the reservoir has seen it, so the first
+	// batch can only come from the first bufferSize + atLeast elements
+	src := Shuffle(Slice(input), 5, rand.New(rand.NewSource(1)))
+	results, err := src.Fetch(3)
+	assert.Nil(t, err)
+	assert.Len(t, results, 3)
+	for _, r := range results {
+		assert.Less(t, r, 8)
+	}
+}
+
+func TestShuffleErr(t *testing.T) {
+	i := 0
+	src := Shuffle(Func(func() (int, error) {
+		i += 1
+		if i > 10 {
+			return 0, errors.New("RESERVOIR ERROR")
+		}
+		return i, nil
+	}), 4, rand.New(rand.NewSource(3)))
+
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+
+	results, err = src.Fetch(10)
+	assert.Equal(t, 0, len(results))
+	assert.EqualError(t, err, "RESERVOIR ERROR")
+
+	results, err = src.Fetch(1)
+	assert.Equal(t, 0, len(results))
+	assert.EqualError(t, err, "RESERVOIR ERROR")
+}
@@ -0,0 +1,211 @@
+package sahil
+
+import (
+	"errors"
+	"sync"
+)
+
+// Sink is the destination at the end of a pipeline, such as a database table
+// or a bulk API, that accepts elements in batches. See Drain.
+type Sink[T any] interface {
+	// WriteBatch writes a batch of elements. The sink may keep the slice.
+	WriteBatch(batch []T) error
+
+	// Flush writes out anything the sink has buffered.
+	Flush() error
+
+	// Close releases the sink. It is called exactly once, after the last
+	// WriteBatch and Flush.
+	Close() error
+}
+
+// Drain fetches every element of p and writes them to sink in batches of at
+// most batchSize, then flushes and closes the sink.
+//
+// If Drain returns nil, every element of p was passed to exactly one
+// successful WriteBatch call, in order. If p or the sink produces an error,
+// Drain stops, closes both p and the sink, and returns the first error. The
+// elements p produced before its error are all written.
+func Drain[T any](p Paginated[T], sink Sink[T], batchSize int) error {
+	return DrainConcurrent(p, []Sink[T]{sink}, batchSize)
+}
+
+// DrainConcurrent is like Drain, but writes batches to several sinks at once,
+// one goroutine per sink. Fetching from p still happens on a single
+// goroutine.
+//
+// Each batch goes to whichever sink is free, so the order in which batches
+// are written is not preserved. If DrainConcurrent returns nil, every
+// element of p was passed to exactly one successful WriteBatch call on one of
+// the sinks.
+//
+// Each sink is only ever called from one goroutine at a time, so a sink that
+// isn't safe for concurrent use can be used as long as it only appears in
+// sinks once.
+//
+// If sinks is empty, there is nowhere to write to: p is closed and an error is
+// returned.
+func DrainConcurrent[T any](p Paginated[T], sinks []Sink[T], batchSize int) error {
+	if len(sinks) == 0 {
+		p.Close()
+		return errors.New("no sinks to drain into")
+	}
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	batches := make(chan []T)
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	var firstErr, fetchErr error
+	fail := func(err error) {
+		stopOnce.Do(func() {
+			firstErr = err
+			close(stop)
+		})
+	}
+
+	var wg sync.WaitGroup
+	for _, sink := range sinks {
+		wg.Add(1)
+		go func(sink Sink[T]) {
+			defer wg.Done()
+			for batch := range batches {
+				select {
+				case <-stop:
+					// keep receiving so the fetching loop never blocks,
+					// but don't write anything more
+					continue
+				default:
+				}
+				if err := sink.WriteBatch(batch); err != nil {
+					fail(err)
+				}
+			}
+		}(sink)
+	}
+
+fetching:
+	for {
+		batch, err := p.FetchRange(batchSize, batchSize)
+		if err != nil {
+			fetchErr = err
+			break
+		}
+		if len(batch) > 0 {
+			select {
+			case batches <- batch:
+			case <-stop:
+				break fetching
+			}
+		}
+		if p.Exhausted() {
+			break
+		}
+	}
+	close(batches)
+	wg.Wait()
+
+	// batches already handed to a sink are still written after p fails, so
+	// what was written is exactly what p produced before its error
+	if fetchErr != nil {
+		fail(fetchErr)
+	}
+
+	select {
+	case <-stop:
+		// something failed: release everything and report the first error
+		p.Close()
+		for _, sink := range sinks {
+			sink.Close()
+		}
+		return firstErr
+	default:
+	}
+
+	var errs []error
+	for _, sink := range sinks {
+		errs = append(errs, sink.Flush())
+	}
+	for _, sink := range sinks {
+		errs = append(errs, sink.Close())
+	}
+	return closeAll(errs...)
+}
+
+// SliceSink is a Sink that keeps everything written to it in memory, meant
+// for tests. It is safe for concurrent use.
+type SliceSink[T any] struct {
+	mutex   sync.Mutex
+	batches [][]T
+	flushes int
+	closed  bool
+}
+
+func (s *SliceSink[T]) WriteBatch(batch []T) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.batches = append(s.batches, batch)
+	return nil
+}
+
+func (s *SliceSink[T]) Flush() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.flushes += 1
+	return nil
+}
+
+func (s *SliceSink[T]) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.closed = true
+	return nil
+}
+
+// Batches returns the batches written so far, in the order they were written.
+func (s *SliceSink[T]) Batches() [][]T {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([][]T{}, s.batches...)
+}
+
+// Elements returns the elements written so far, in the order they were
+// written.
+func (s *SliceSink[T]) Elements() []T {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var out []T
+	for _, batch := range s.batches {
+		out = append(out, batch...)
+	}
+	return out
+}
+
+// Flushes returns the number of times the sink has been flushed.
+func (s *SliceSink[T]) Flushes() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.flushes
+}
+
+// Closed returns whether the sink has been closed.
+func (s *SliceSink[T]) Closed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.closed
+}
+
+type funcSink[T any] struct {
+	fn func([]T) error
+}
+
+// FuncSink creates a Sink that calls fn on each batch. Flush and Close do
+// nothing.
+func FuncSink[T any](fn func([]T) error) Sink[T] {
+	return funcSink[T]{fn}
+}
+
+func (f funcSink[T]) WriteBatch(batch []T) error { return f.fn(batch) }
+func (f funcSink[T]) Flush() error               { return nil }
+func (f funcSink[T]) Close() error               { return nil }
@@ -0,0 +1,124 @@
+package sahil
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrain(t *testing.T) {
+	var input []int
+	for i := 0; i < 10; i++ {
+		input = append(input, i)
+	}
+
+	sink := &SliceSink[int]{}
+	assert.Nil(t, Drain[int](Slice(input), sink, 4))
+
+	assert.EqualValues(t, [][]int{{0, 1, 2, 3}, {4, 5, 6, 7}, {8, 9}}, sink.Batches())
+	assert.Equal(t, 1, sink.Flushes())
+	assert.True(t, sink.Closed())
+}
+
+func TestDrainBatchSize(t *testing.T) {
+	// FlatMap overshoots, but Drain never writes more than batchSize at once
+	src := FlatMap(Slice([]int{5, 5}), func(n int) (Paginated[int], error) {
+		return Slice(make([]int, n)), nil
+	})
+
+	var sizes []int
+	assert.Nil(t, Drain(src, FuncSink(func(batch []int) error {
+		sizes = append(sizes, len(batch))
+		return nil
+	}), 3))
+	assert.EqualValues(t, []int{3, 3, 3, 1}, sizes)
+}
+
+func TestDrainSourceErr(t *testing.T) {
+	i := 0
+	src := Func(func() (int, error) {
+		i += 1
+		if i > 5 {
+			return 0, errors.New("UPSTREAM ERROR")
+		}
+		return i, nil
+	})
+
+	sink := &SliceSink[int]{}
+	assert.EqualError(t, Drain[int](src, sink, 2), "UPSTREAM ERROR")
+	assert.EqualValues(t, []int{1, 2, 3, 4}, sink.Elements())
+	assert.Equal(t, 0, sink.Flushes())
+	assert.True(t, sink.Closed())
+}
+
+func TestDrainSinkErr(t *testing.T) {
+	closed := 0
+	src := Slice([]int{1, 2, 3, 4, 5}).OnClose(closeCounter(&closed, nil))
+
+	writes := 0
+	err := Drain(src, FuncSink(func(batch []int) error {
+		writes += 1
+		if writes == 2 {
+			return errors.New("SINK ERROR")
+		}
+		return nil
+	}), 2)
+	assert.EqualError(t, err, "SINK ERROR")
+	assert.Equal(t, 2, writes)
+	assert.Equal(t, 1, closed)
+}
+
+func TestDrainConcurrent(t *testing.T) {
+	var input []int
+	for i := 0; i < 1000; i++ {
+		input = append(input, i)
+	}
+
+	sinks := []*SliceSink[int]{{}, {}, {}, {}}
+	err := DrainConcurrent(Slice(input), []Sink[int]{sinks[0], sinks[1], sinks[2], sinks[3]}, 7)
+	assert.Nil(t, err)
+
+	var results []int
+	for _, sink := range sinks {
+		results = append(results, sink.Elements()...)
+		assert.Equal(t, 1, sink.Flushes())
+		assert.True(t, sink.Closed())
+	}
+	sort.Ints(results)
+	assert.EqualValues(t, input, results)
+}
+
+func TestDrainConcurrentErr(t *testing.T) {
+	var input []int
+	for i := 0; i < 1000; i++ {
+		input = append(input, i)
+	}
+
+	var mutex sync.Mutex
+	writes := 0
+	failing := FuncSink(func(batch []int) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		writes += 1
+		if writes == 3 {
+			return errors.New("SINK ERROR")
+		}
+		return nil
+	})
+
+	err := DrainConcurrent(Slice(input), []Sink[int]{failing, failing}, 10)
+	assert.EqualError(t, err, "SINK ERROR")
+	assert.Less(t, writes, 10)
+}
+
+func TestDrainConcurrentNoSinks(t *testing.T) {
+	closed := 0
+	src := Slice([]int{1, 2, 3}).OnClose(closeCounter(&closed, nil))
+
+	err := DrainConcurrent(src, nil, 2)
+	assert.EqualError(t, err, "no sinks to drain into")
+	assert.Equal(t, 1, closed)
+}
@@ -1,7 +1,10 @@
 package sahil
 
+import "fmt"
+
 type fetchSlice[T any] struct {
-	slice []T
+	slice  []T
+	offset int
 }
 
 // Slice takes a slice of values and creates a Paginated whose values are the
@@ -9,6 +12,9 @@ type fetchSlice[T any] struct {
 //
 // You rarely need this by itself, but you can use it to provide input to other
 // Paginated-consuming APIs.
+//
+// A Slice's checkpoint is just its position in the slice, so resuming it
+// requires the same slice.
 func Slice[T any](slice []T) Paginated[T] {
 	mySlice := make([]T, len(slice))
 	copy(mySlice, slice)
@@ -21,9 +27,28 @@ func Slice[T any](slice []T) Paginated[T] {
 }
 
 func (f *fetchSlice[T]) Fetch(atLeast int) ([]T, error) {
-	// defer to the buffering implementation
-	// and make sure future calls can only produce nil
-	out := f.slice
-	f.slice = nil
+	// hand out exactly what was asked for, so nothing needs to be buffered
+	end := f.offset + atLeast
+	if end > len(f.slice) {
+		end = len(f.slice)
+	}
+	out := f.slice[f.offset:end]
+	f.offset = end
 	return out, nil
 }
+
+func (f *fetchSlice[T]) Checkpoint() ([]byte, error) {
+	return encodeCheckpoint(f.offset)
+}
+
+func (f *fetchSlice[T]) Restore(checkpoint []byte) error {
+	var offset int
+	if err := decodeCheckpoint(checkpoint, &offset); err != nil {
+		return err
+	}
+	if offset < 0 || offset > len(f.slice) {
+		return fmt.Errorf("checkpoint is at element %d of a %d-element slice", offset, len(f.slice))
+	}
+	f.offset = offset
+	return nil
+}
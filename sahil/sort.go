@@ -0,0 +1,319 @@
+package sahil
+
+import (
+	"container/heap"
+	"sort"
+)
+
+type externalSort[T any] struct {
+	underlying Paginated[T]
+	less       func(a, b T) bool
+	memLimit   int
+	fanIn      int // most runs merged at once
+	started    bool
+	sorted     []T // used when everything fit in memory
+	runs       *runHeap[T]
+}
+
+// Sort sorts the elements of a Paginated according to less. The sort is
+// stable.
+//
+// Nothing can be produced until the whole source has been read, so the first
+// call to Fetch consumes the underlying Paginated entirely.
+//
+// At most memLimit elements are held in memory at a time while reading. If the
+// source is bigger than that, Sort writes sorted runs of memLimit elements to
+// temporary files, then merges them as the output is fetched. A memLimit of 0
+// or less means the whole source is sorted in memory.
+//
+// At most 64 runs are merged at once, so at most 64 temporary files are open
+// at a time. If there are more runs than that, they are merged in several
+// passes before any output is produced.
+//
+// Runs are written using encoding/gob, so T must be something gob can encode:
+// for instance, only the exported fields of a struct survive the trip. The
+// temporary files are removed as each run is used up.
+func Sort[T any](p Paginated[T], less func(a, b T) bool, memLimit int) Paginated[T] {
+	return wrap[T](&externalSort[T]{
+		underlying: p,
+		less:       less,
+		memLimit:   memLimit,
+		fanIn:      sortFanIn,
+	})
+}
+
+// sortFanIn is the most runs Sort merges at once.
+const sortFanIn = 64
+
+func (s *externalSort[T]) Fetch(atLeast int) ([]T, error) {
+	if !s.started {
+		s.started = true
+		if err := s.readAll(); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.runs == nil {
+		n := atLeast
+		if n > len(s.sorted) {
+			n = len(s.sorted)
+		}
+		out := s.sorted[:n]
+		s.sorted = s.sorted[n:]
+		return out, nil
+	}
+
+	var out []T
+	for len(out) < atLeast && s.runs.Len() > 0 {
+		t, err := s.runs.pop()
+		if err != nil {
+			s.runs.cleanup()
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+func (s *externalSort[T]) readAll() error {
+	batchSize := s.memLimit
+	if batchSize < 1 {
+		batchSize = topKBatchSize
+	}
+
+	var buf []T
+	var runs []*sortRun[T]
+	cleanup := func() {
+		for _, r := range runs {
+			r.cleanup()
+		}
+	}
+
+	for {
+		nWanted := batchSize
+		if s.memLimit > 0 {
+			nWanted = s.memLimit - len(buf)
+		}
+
+		batch, err := s.underlying.FetchRange(nWanted, nWanted)
+		if err != nil {
+			cleanup()
+			return err
+		}
+		buf = append(buf, batch...)
+		done := len(batch) < nWanted
+
+		if s.memLimit > 0 && (len(buf) >= s.memLimit || (done && len(runs) > 0)) {
+			s.sortSlice(buf)
+			r, err := writeRun(buf, len(runs))
+			if err != nil {
+				cleanup()
+				return err
+			}
+			runs = append(runs, r)
+			buf = nil
+		}
+
+		if done {
+			break
+		}
+	}
+
+	if len(runs) == 0 {
+		s.sortSlice(buf)
+		s.sorted = buf
+		return nil
+	}
+
+	// merge in several passes if there are too many runs to open at once
+	for len(runs) > s.fanIn {
+		var merged []*sortRun[T]
+		for i := 0; i < len(runs); i += s.fanIn {
+			end := i + s.fanIn
+			if end > len(runs) {
+				end = len(runs)
+			}
+
+			// runs are numbered by position, so ties still go to the
+			// earlier run
+			r, err := s.mergeRuns(runs[i:end], len(merged))
+			if err != nil {
+				runs = append(merged, runs[end:]...)
+				cleanup()
+				return err
+			}
+			merged = append(merged, r)
+		}
+		runs = merged
+	}
+
+	h, err := newRunHeap(runs, s.less)
+	if err != nil {
+		return err
+	}
+	s.runs = h
+	return nil
+}
+
+// mergeRuns merges runs into a single new run, cleaning them up.
+func (s *externalSort[T]) mergeRuns(runs []*sortRun[T], index int) (*sortRun[T], error) {
+	h, err := newRunHeap(runs, s.less)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := newSpillFile[T]("", "sahil-sort-*", GobCodec)
+	if err != nil {
+		h.cleanup()
+		return nil, err
+	}
+	r := &sortRun[T]{index: index, file: file}
+
+	for h.Len() > 0 {
+		t, err := h.pop()
+		if err == nil {
+			err = file.write([]T{t})
+		}
+		if err != nil {
+			h.cleanup()
+			r.cleanup()
+			return nil, err
+		}
+	}
+	if err := file.finish(); err != nil {
+		r.cleanup()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *externalSort[T]) sortSlice(ts []T) {
+	sort.SliceStable(ts, func(i, j int) bool { return s.less(ts[i], ts[j]) })
+}
+
+// sortRun is a sorted run of elements that has been written to disk.
+type sortRun[T any] struct {
+	index int // used to break ties, keeping the merge stable
+	file  *spillFile[T]
+	head  T
+}
+
+func writeRun[T any](ts []T, index int) (*sortRun[T], error) {
+	file, err := newSpillFile[T]("", "sahil-sort-*", GobCodec)
+	if err != nil {
+		return nil, err
+	}
+	r := &sortRun[T]{index: index, file: file}
+
+	// only the read handle stays open from here on
+	err = file.write(ts)
+	if err == nil {
+		err = file.finish()
+	}
+	if err != nil {
+		r.cleanup()
+		return nil, err
+	}
+	return r, nil
+}
+
+// next reads the next element of the run into head, returning false once the
+// run is used up.
+func (r *sortRun[T]) next() (bool, error) {
+	if r.file.count == 0 {
+		return false, nil
+	}
+	ts, err := r.file.read(1)
+	if err != nil {
+		return false, err
+	}
+	r.head = ts[0]
+	return true, nil
+}
+
+func (r *sortRun[T]) cleanup() {
+	if r.file == nil {
+		return
+	}
+	r.file.cleanup()
+	r.file = nil
+}
+
+type runHeap[T any] struct {
+	runs []*sortRun[T]
+	less func(a, b T) bool
+}
+
+func (h *runHeap[T]) Len() int { return len(h.runs) }
+func (h *runHeap[T]) Less(i, j int) bool {
+	a, b := h.runs[i], h.runs[j]
+	if h.less(a.head, b.head) {
+		return true
+	}
+	if h.less(b.head, a.head) {
+		return false
+	}
+	return a.index < b.index
+}
+func (h *runHeap[T]) Swap(i, j int) { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+func (h *runHeap[T]) Push(x any)    { h.runs = append(h.runs, x.(*sortRun[T])) }
+func (h *runHeap[T]) Pop() any {
+	last := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return last
+}
+
+// newRunHeap reads the first element of each run and arranges them for
+// merging. If that fails, every run is cleaned up.
+func newRunHeap[T any](runs []*sortRun[T], less func(a, b T) bool) (*runHeap[T], error) {
+	h := &runHeap[T]{less: less}
+	for i, r := range runs {
+		ok, err := r.next()
+		if err != nil {
+			h.cleanup()
+			for _, r := range runs[i:] {
+				r.cleanup()
+			}
+			return nil, err
+		}
+		if ok {
+			h.runs = append(h.runs, r)
+		} else {
+			r.cleanup()
+		}
+	}
+	heap.Init(h)
+	return h, nil
+}
+
+// pop removes the smallest element, advancing the run it came from.
+func (h *runHeap[T]) pop() (T, error) {
+	r := h.runs[0]
+	t := r.head
+
+	ok, err := r.next()
+	if err != nil {
+		return t, err
+	}
+	if ok {
+		heap.Fix(h, 0)
+	} else {
+		heap.Pop(h)
+		r.cleanup()
+	}
+	return t, nil
+}
+
+func (h *runHeap[T]) cleanup() {
+	for _, r := range h.runs {
+		r.cleanup()
+	}
+	h.runs = nil
+}
+
+func (s *externalSort[T]) Close() error {
+	if s.runs != nil {
+		s.runs.cleanup()
+	}
+	return s.underlying.Close()
+}
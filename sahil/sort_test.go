@@ -0,0 +1,180 @@
+package sahil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sortRow struct {
+	Key   int
+	Value string
+}
+
+func TestSortInMemory(t *testing.T) {
+	src := Sort(Slice([]int{5, 3, 9, 1, 7}), func(a, b int) bool { return a < b }, 0)
+
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 3}, results)
+
+	results, err = src.Fetch(5)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{5, 7, 9}, results)
+}
+
+func TestSortSpill(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	var input []sortRow
+	for i := 0; i < 100; i++ {
+		input = append(input, sortRow{Key: (i * 37) % 10, Value: string(rune('a' + i%26))})
+	}
+
+	src := Sort(Slice(input), func(a, b sortRow) bool { return a.Key < b.Key }, 16)
+
+	var results []sortRow
+	for {
+		batch, err := src.Fetch(13)
+		assert.Nil(t, err)
+		results = append(results, batch...)
+		if len(batch) < 13 {
+			break
+		}
+	}
+
+	assert.Len(t, results, 100)
+	for i := 1; i < len(results); i++ {
+		assert.LessOrEqual(t, results[i-1].Key, results[i].Key)
+	}
+
+	// stable: rows with the same key keep their original order
+	var expected []sortRow
+	for _, row := range input {
+		if row.Key == 3 {
+			expected = append(expected, row)
+		}
+	}
+	var actual []sortRow
+	for _, row := range results {
+		if row.Key == 3 {
+			actual = append(actual, row)
+		}
+	}
+	assert.EqualValues(t, expected, actual)
+
+	// every run has been cleaned up
+	leftovers, err := filepath.Glob(filepath.Join(tmp, "sahil-sort-*"))
+	assert.Nil(t, err)
+	assert.Empty(t, leftovers)
+}
+
+func TestSortErr(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	i := 0
+	src := Sort(Func(func() (int, error) {
+		i += 1
+		if i > 50 {
+			return 0, errors.New("SORT ERROR")
+		}
+		return -i, nil
+	}), func(a, b int) bool { return a < b }, 10)
+
+	results, err := src.Fetch(1)
+	assert.Equal(t, 0, len(results))
+	assert.EqualError(t, err, "SORT ERROR")
+
+	entries, err := os.ReadDir(tmp)
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSortClose(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	var input []int
+	for i := 0; i < 100; i++ {
+		input = append(input, 100-i)
+	}
+	src := Sort(Slice(input), func(a, b int) bool { return a < b }, 10)
+
+	results, err := src.Fetch(5)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 2, 3, 4, 5}, results)
+
+	entries, err := os.ReadDir(tmp)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, entries)
+
+	// abandoning the sort removes its runs
+	assert.Nil(t, src.Close())
+	entries, err = os.ReadDir(tmp)
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSortMultiPass(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	var input []sortRow
+	for i := 0; i < 500; i++ {
+		input = append(input, sortRow{Key: (i * 37) % 10, Value: fmt.Sprint(i)})
+	}
+
+	// 50 runs, merged 3 at a time
+	src := wrap[sortRow](&externalSort[sortRow]{
+		underlying: Slice(input),
+		less:       func(a, b sortRow) bool { return a.Key < b.Key },
+		memLimit:   10,
+		fanIn:      3,
+	})
+
+	results, err := src.Fetch(1000)
+	assert.Nil(t, err)
+
+	// the same as a stable sort in memory
+	expected := append([]sortRow{}, input...)
+	sort.SliceStable(expected, func(i, j int) bool { return expected[i].Key < expected[j].Key })
+	assert.EqualValues(t, expected, results)
+
+	entries, err := os.ReadDir(tmp)
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSortOpenFiles(t *testing.T) {
+	countFDs := func() int {
+		entries, err := os.ReadDir("/proc/self/fd")
+		if err != nil {
+			t.Skip("can't count open files on this platform")
+		}
+		return len(entries)
+	}
+
+	t.Setenv("TMPDIR", t.TempDir())
+	before := countFDs()
+
+	var input []int
+	for i := 0; i < 20000; i++ {
+		input = append(input, (i*7919)%20000)
+	}
+	src := Sort(Slice(input), func(a, b int) bool { return a < b }, 100)
+
+	results, err := src.Fetch(5)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{0, 1, 2, 3, 4}, results)
+
+	// 200 runs, but only one merge's worth of them are open
+	assert.LessOrEqual(t, countFDs()-before, sortFanIn)
+	assert.Nil(t, src.Close())
+}
@@ -0,0 +1,292 @@
+package sahil
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// Codec describes how elements are written to disk when they spill out of
+// memory. GobCodec and JSONCodec are provided.
+type Codec interface {
+	NewEncoder(w io.Writer) Encoder
+	NewDecoder(r io.Reader) Decoder
+}
+
+// Encoder writes a stream of values. *gob.Encoder and *json.Encoder are both
+// Encoders.
+type Encoder interface {
+	Encode(v any) error
+}
+
+// Decoder reads back a stream of values written by an Encoder. *gob.Decoder
+// and *json.Decoder are both Decoders.
+type Decoder interface {
+	Decode(v any) error
+}
+
+type gobCodec struct{}
+
+func (gobCodec) NewEncoder(w io.Writer) Encoder { return gob.NewEncoder(w) }
+func (gobCodec) NewDecoder(r io.Reader) Decoder { return gob.NewDecoder(r) }
+
+type jsonCodec struct{}
+
+func (jsonCodec) NewEncoder(w io.Writer) Encoder { return json.NewEncoder(w) }
+func (jsonCodec) NewDecoder(r io.Reader) Decoder { return json.NewDecoder(r) }
+
+// GobCodec writes elements using encoding/gob. It is fast, but only the
+// exported fields of a struct survive the trip.
+var GobCodec Codec = gobCodec{}
+
+// JSONCodec writes elements using encoding/json. It is slower than GobCodec,
+// but the files are readable by people.
+var JSONCodec Codec = jsonCodec{}
+
+// SpillPolicy says when a Paginated should move elements it is holding onto
+// from memory to a temporary file.
+type SpillPolicy struct {
+	// Threshold is the most elements the Paginated's buffer will hold in memory.
+	// 0 or less turns spilling off.
+	Threshold int
+
+	// Codec is used to write the elements to disk. The default is GobCodec.
+	Codec Codec
+
+	// Dir is the directory for the temporary file. The default is os.TempDir().
+	Dir string
+}
+
+// WithSpill makes p move buffered elements to a temporary file once it holds
+// more than policy.Threshold of them, then stream them back as they're
+// fetched. It returns p.
+//
+// A Paginated only buffers elements when its data source returns more than
+// the caller asked for: for instance, when a FlatMap expands one input into
+// millions of outputs. This keeps that buffer from taking over the heap.
+//
+// The file is removed once everything in it has been read back, and when the
+// Paginated is closed, which happens automatically once it runs out or
+// produces an error.
+//
+// Call WithSpill before fetching from p. Changing the policy once elements
+// have spilled isn't supported.
+func (p Paginated[T]) WithSpill(policy SpillPolicy) Paginated[T] {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.underlying.spill != nil {
+		if policy.Codec == nil {
+			policy.Codec = GobCodec
+		}
+		p.underlying.spill.policy = policy
+	}
+	return p
+}
+
+// spillState is the part of a buffer that lives on disk.
+type spillState[T any] struct {
+	policy SpillPolicy
+	file   *spillFile[T]
+}
+
+func (s *spillState[T]) count() int {
+	if s == nil || s.file == nil {
+		return 0
+	}
+	return s.file.count
+}
+
+// read reads up to n elements from disk. Once the file has been read to the
+// end it is removed, and the next spill starts a new one, so the file doesn't
+// keep growing for as long as the Paginated lives.
+func (s *spillState[T]) read(n int) ([]T, error) {
+	out, err := s.file.read(n)
+	if err != nil {
+		return nil, err
+	}
+	if s.file.count == 0 {
+		s.cleanup()
+	}
+	return out, nil
+}
+
+func (s *spillState[T]) cleanup() {
+	if s == nil || s.file == nil {
+		return
+	}
+	s.file.cleanup()
+	s.file = nil
+}
+
+// length is how many elements are buffered, in memory or on disk.
+func (b buffered[T]) length() int {
+	return len(*b.buffer) + b.spill.count()
+}
+
+// push adds elements to the end of the buffer, spilling them to disk if
+// there are too many.
+func (b buffered[T]) push(ts []T) error {
+	s := b.spill
+	if s == nil || s.policy.Threshold <= 0 {
+		*b.buffer = append(*b.buffer, ts...)
+		return nil
+	}
+
+	// anything already on disk comes first, so everything new goes after it
+	if s.count() == 0 {
+		room := s.policy.Threshold - len(*b.buffer)
+		if room >= len(ts) {
+			*b.buffer = append(*b.buffer, ts...)
+			return nil
+		}
+		if room > 0 {
+			*b.buffer = append(*b.buffer, ts[:room]...)
+			ts = ts[room:]
+		}
+	}
+
+	if s.file == nil {
+		file, err := newSpillFile[T](s.policy.Dir, "sahil-spill-*", s.policy.Codec)
+		if err != nil {
+			return err
+		}
+		s.file = file
+	}
+	return s.file.write(ts)
+}
+
+// take removes up to n elements from the front of the buffer.
+func (b buffered[T]) take(n int) ([]T, error) {
+	var chunk []T
+	if len(*b.buffer) > n {
+		chunk = (*b.buffer)[:n]
+		*b.buffer = (*b.buffer)[n:]
+		return chunk, nil
+	}
+
+	chunk = *b.buffer
+	*b.buffer = nil
+
+	if rest := n - len(chunk); rest > 0 && b.spill.count() > 0 {
+		fromDisk, err := b.spill.read(rest)
+		if err != nil {
+			return nil, err
+		}
+		chunk = append(chunk, fromDisk...)
+	}
+	return chunk, nil
+}
+
+// unspill moves everything on disk back into memory.
+func (b buffered[T]) unspill() error {
+	if b.spill.count() == 0 {
+		return nil
+	}
+	fromDisk, err := b.spill.read(b.spill.count())
+	if err != nil {
+		return err
+	}
+	*b.buffer = append(*b.buffer, fromDisk...)
+	return nil
+}
+
+// spillFile is a temporary file of elements, written at one end and read from
+// the other.
+//
+// The read handle is only opened on the first read, and finish closes the
+// write handle once nothing more will be written, so a file that is only
+// written, or only read, holds one file descriptor.
+type spillFile[T any] struct {
+	name    string
+	codec   Codec
+	file    *os.File // nil after finish
+	writer  *bufio.Writer
+	encoder Encoder
+	reader  *os.File // nil until the first read
+	decoder Decoder
+	count   int // written but not yet read
+}
+
+func newSpillFile[T any](dir string, pattern string, codec Codec) (*spillFile[T], error) {
+	file, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := bufio.NewWriter(file)
+	return &spillFile[T]{
+		name:    file.Name(),
+		codec:   codec,
+		file:    file,
+		writer:  writer,
+		encoder: codec.NewEncoder(writer),
+	}, nil
+}
+
+func (f *spillFile[T]) write(ts []T) error {
+	for i := range ts {
+		if err := f.encoder.Encode(&ts[i]); err != nil {
+			return err
+		}
+	}
+	f.count += len(ts)
+	return nil
+}
+
+// finish flushes everything written and closes the write handle. Only read
+// can be used afterwards.
+func (f *spillFile[T]) finish() error {
+	err := f.writer.Flush()
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	f.file, f.writer, f.encoder = nil, nil, nil
+	return err
+}
+
+// read reads up to n elements, in the order they were written.
+func (f *spillFile[T]) read(n int) ([]T, error) {
+	if n > f.count {
+		n = f.count
+	}
+	if n == 0 {
+		return nil, nil
+	}
+
+	// everything written so far has to reach the file before it can be read
+	if f.writer != nil {
+		if err := f.writer.Flush(); err != nil {
+			return nil, err
+		}
+	}
+	if f.reader == nil {
+		reader, err := os.Open(f.name)
+		if err != nil {
+			return nil, err
+		}
+		f.reader = reader
+		f.decoder = f.codec.NewDecoder(bufio.NewReader(reader))
+	}
+
+	out := make([]T, n)
+	for i := range out {
+		if err := f.decoder.Decode(&out[i]); err != nil {
+			return nil, err
+		}
+	}
+	f.count -= n
+	return out, nil
+}
+
+func (f *spillFile[T]) cleanup() {
+	if f.reader != nil {
+		f.reader.Close()
+	}
+	if f.file != nil {
+		f.file.Close()
+	}
+	os.Remove(f.name)
+}
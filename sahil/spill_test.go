@@ -0,0 +1,166 @@
+package sahil
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type spillRow struct {
+	Id   int
+	Name string
+}
+
+// burstySource produces `batch` rows every time it is called, whatever it was
+// asked for, up to `total` rows.
+type burstySource struct {
+	next, batch, total int
+}
+
+func (b *burstySource) Fetch(atLeast int) ([]spillRow, error) {
+	var out []spillRow
+	for i := 0; i < b.batch && b.next < b.total; i++ {
+		out = append(out, spillRow{Id: b.next, Name: "Desmodus"})
+		b.next += 1
+	}
+	return out, nil
+}
+
+func (b *burstySource) Checkpoint() ([]byte, error) {
+	return encodeCheckpoint(b.next)
+}
+
+func (b *burstySource) Restore(checkpoint []byte) error {
+	return decodeCheckpoint(checkpoint, &b.next)
+}
+
+func testSpill(t *testing.T, codec Codec) {
+	dir := t.TempDir()
+	src := FromSource[spillRow](&burstySource{batch: 20, total: 100}).WithSpill(SpillPolicy{
+		Threshold: 5,
+		Codec:     codec,
+		Dir:       dir,
+	})
+
+	var results []spillRow
+	for {
+		batch, err := src.Fetch(3)
+		assert.Nil(t, err)
+		results = append(results, batch...)
+
+		if len(batch) == 3 {
+			entries, err := os.ReadDir(dir)
+			assert.Nil(t, err)
+			assert.Len(t, entries, 1)
+		}
+		if len(batch) < 3 {
+			break
+		}
+	}
+
+	assert.Len(t, results, 100)
+	for i, row := range results {
+		assert.Equal(t, spillRow{Id: i, Name: "Desmodus"}, row)
+	}
+
+	// the file goes away once the Paginated runs out
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSpillGob(t *testing.T) {
+	testSpill(t, GobCodec)
+}
+
+func TestSpillJSON(t *testing.T) {
+	testSpill(t, JSONCodec)
+}
+
+func TestSpillUnderThreshold(t *testing.T) {
+	dir := t.TempDir()
+	src := FromSource[spillRow](&burstySource{batch: 4, total: 10}).WithSpill(SpillPolicy{
+		Threshold: 5,
+		Dir:       dir,
+	})
+
+	results, err := src.Fetch(1)
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSpillClose(t *testing.T) {
+	dir := t.TempDir()
+	src := FromSource[spillRow](&burstySource{batch: 50, total: 100}).WithSpill(SpillPolicy{
+		Threshold: 5,
+		Dir:       dir,
+	})
+
+	_, err := src.Fetch(1)
+	assert.Nil(t, err)
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+
+	assert.Nil(t, src.Close())
+	entries, err = os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSpillFileStaysSmall(t *testing.T) {
+	dir := t.TempDir()
+	src := FromSource[spillRow](&burstySource{batch: 50, total: 100000}).WithSpill(SpillPolicy{
+		Threshold: 5,
+		Dir:       dir,
+	})
+
+	// each burst spills 35 rows, which are all read back before the next one,
+	// so the file should never hold much more than that
+	var biggest int64
+	for i := 0; i < 1900; i++ {
+		_, err := src.Fetch(5)
+		assert.Nil(t, err)
+
+		entries, err := os.ReadDir(dir)
+		assert.Nil(t, err)
+		assert.LessOrEqual(t, len(entries), 1)
+		for _, entry := range entries {
+			info, err := entry.Info()
+			assert.Nil(t, err)
+			if info.Size() > biggest {
+				biggest = info.Size()
+			}
+		}
+	}
+	assert.Greater(t, biggest, int64(0))
+	assert.Less(t, biggest, int64(4096))
+}
+
+func TestSpillCheckpoint(t *testing.T) {
+	build := func() Paginated[spillRow] {
+		return FromSource[spillRow](&burstySource{batch: 50, total: 100}).WithSpill(SpillPolicy{
+			Threshold: 5,
+			Dir:       t.TempDir(),
+		})
+	}
+
+	src := build()
+	_, err := src.Fetch(10)
+	assert.Nil(t, err)
+
+	checkpoint, err := src.Checkpoint()
+	assert.Nil(t, err)
+
+	resumed, err := Resume(build(), checkpoint)
+	assert.Nil(t, err)
+	results, err := resumed.Fetch(200)
+	assert.Nil(t, err)
+	assert.Len(t, results, 80)
+	assert.Equal(t, 20, results[0].Id)
+}
@@ -0,0 +1,163 @@
+// Package sqlsink writes Paginated streams into SQL tables with multi-row
+// INSERT statements.
+package sqlsink
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Nyeogmi/sahil-go/sahil"
+)
+
+// Dialect is the placeholder style of a database.
+type Dialect int
+
+const (
+	// Question uses ? placeholders, as in MySQL and SQLite.
+	Question Dialect = iota
+
+	// Dollar uses numbered $1, $2, ... placeholders, as in PostgreSQL.
+	Dollar
+)
+
+// DefaultMaxPlaceholders is the placeholder limit used when Options doesn't
+// give one. It is the lowest limit among common databases (older SQLite).
+const DefaultMaxPlaceholders = 999
+
+// Options describes the table a Sink inserts into.
+type Options struct {
+	// Table and Columns name what to insert into. They are used as-is, so
+	// quote them if they need it.
+	Table   string
+	Columns []string
+
+	Dialect Dialect
+
+	// MaxPlaceholders is the most placeholders to put in one statement.
+	// Batches with more values than that are split across several
+	// statements. The default is DefaultMaxPlaceholders.
+	MaxPlaceholders int
+
+	// Transaction makes each batch its own transaction, so that a batch split
+	// across several statements is still inserted all or nothing.
+	Transaction bool
+}
+
+type sink[T any] struct {
+	ctx  context.Context
+	db   *sql.DB
+	opts Options
+	row  func(T) ([]any, error)
+}
+
+// New creates a Sink that inserts each batch into a table of db. row turns an
+// element into the values for opts.Columns, in the same order.
+//
+// The Sink doesn't own db: closing the Sink leaves it open.
+func New[T any](ctx context.Context, db *sql.DB, opts Options, row func(T) ([]any, error)) sahil.Sink[T] {
+	if opts.MaxPlaceholders < 1 {
+		opts.MaxPlaceholders = DefaultMaxPlaceholders
+	}
+	return &sink[T]{ctx: ctx, db: db, opts: opts, row: row}
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (s *sink[T]) WriteBatch(batch []T) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	if len(s.opts.Columns) == 0 {
+		return fmt.Errorf("no columns given for table %s", s.opts.Table)
+	}
+
+	rowsPerStatement := s.opts.MaxPlaceholders / len(s.opts.Columns)
+	if rowsPerStatement < 1 {
+		return fmt.Errorf("%d columns won't fit in %d placeholders", len(s.opts.Columns), s.opts.MaxPlaceholders)
+	}
+
+	if !s.opts.Transaction {
+		return s.insert(s.db, batch, rowsPerStatement)
+	}
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := s.insert(tx, batch, rowsPerStatement); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sink[T]) insert(db execer, batch []T, rowsPerStatement int) error {
+	for len(batch) > 0 {
+		n := len(batch)
+		if n > rowsPerStatement {
+			n = rowsPerStatement
+		}
+
+		var args []any
+		for _, t := range batch[:n] {
+			values, err := s.row(t)
+			if err != nil {
+				return err
+			}
+			if len(values) != len(s.opts.Columns) {
+				return fmt.Errorf("row has %d values for %d columns", len(values), len(s.opts.Columns))
+			}
+			args = append(args, values...)
+		}
+
+		if _, err := db.ExecContext(s.ctx, s.statement(n), args...); err != nil {
+			return err
+		}
+		batch = batch[n:]
+	}
+	return nil
+}
+
+// statement builds an INSERT for nRows rows.
+func (s *sink[T]) statement(nRows int) string {
+	var b strings.Builder
+	b.WriteString("INSERT INTO ")
+	b.WriteString(s.opts.Table)
+	b.WriteString(" (")
+	b.WriteString(strings.Join(s.opts.Columns, ", "))
+	b.WriteString(") VALUES ")
+
+	n := 0
+	for i := 0; i < nRows; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for j := range s.opts.Columns {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			n += 1
+			if s.opts.Dialect == Dollar {
+				b.WriteString("$" + strconv.Itoa(n))
+			} else {
+				b.WriteString("?")
+			}
+		}
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+func (s *sink[T]) Flush() error {
+	return nil
+}
+
+func (s *sink[T]) Close() error {
+	return nil
+}
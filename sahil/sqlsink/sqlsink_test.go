@@ -0,0 +1,153 @@
+package sqlsink
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Nyeogmi/sahil-go/sahil"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDriver records what is done to it, failing any statement that
+// mentions failOn.
+type fakeDriver struct {
+	mutex  sync.Mutex
+	log    []string
+	failOn string
+}
+
+func (d *fakeDriver) record(entry string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.log = append(d.log, entry)
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.d, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) {
+	c.d.record("BEGIN")
+	return fakeTx{c.d}, nil
+}
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	entry := fmt.Sprint(s.query, " ", args)
+	s.d.record(entry)
+	if s.d.failOn != "" && strings.Contains(entry, s.d.failOn) {
+		return nil, errors.New("CONSTRAINT VIOLATION")
+	}
+	return driver.RowsAffected(1), nil
+}
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+type fakeTx struct{ d *fakeDriver }
+
+func (t fakeTx) Commit() error   { t.d.record("COMMIT"); return nil }
+func (t fakeTx) Rollback() error { t.d.record("ROLLBACK"); return nil }
+
+var nDrivers int
+
+func openFake(t *testing.T, failOn string) (*sql.DB, *fakeDriver) {
+	d := &fakeDriver{failOn: failOn}
+	nDrivers += 1
+	name := fmt.Sprintf("fake%d", nDrivers)
+	sql.Register(name, d)
+
+	db, err := sql.Open(name, "")
+	assert.Nil(t, err)
+	db.SetMaxOpenConns(1)
+	return db, d
+}
+
+type sighting struct {
+	species string
+	count   int
+}
+
+func sightingRow(s sighting) ([]any, error) {
+	return []any{s.species, s.count}, nil
+}
+
+var sightings = []sighting{
+	{"Myotis lucifugus", 12},
+	{"Eptesicus fuscus", 3},
+	{"Lasiurus borealis", 1},
+}
+
+func TestSink(t *testing.T) {
+	db, d := openFake(t, "")
+	sink := New(context.Background(), db, Options{
+		Table:   "sightings",
+		Columns: []string{"species", "count"},
+	}, sightingRow)
+
+	assert.Nil(t, sahil.Drain(sahil.Slice(sightings), sink, 5))
+	assert.EqualValues(t, []string{
+		"INSERT INTO sightings (species, count) VALUES (?, ?), (?, ?), (?, ?) " +
+			"[Myotis lucifugus 12 Eptesicus fuscus 3 Lasiurus borealis 1]",
+	}, d.log)
+}
+
+func TestSinkMaxPlaceholders(t *testing.T) {
+	db, d := openFake(t, "")
+	sink := New(context.Background(), db, Options{
+		Table:           "sightings",
+		Columns:         []string{"species", "count"},
+		Dialect:         Dollar,
+		MaxPlaceholders: 5,
+		Transaction:     true,
+	}, sightingRow)
+
+	assert.Nil(t, sahil.Drain(sahil.Slice(sightings), sink, 5))
+	assert.EqualValues(t, []string{
+		"BEGIN",
+		"INSERT INTO sightings (species, count) VALUES ($1, $2), ($3, $4) " +
+			"[Myotis lucifugus 12 Eptesicus fuscus 3]",
+		"INSERT INTO sightings (species, count) VALUES ($1, $2) [Lasiurus borealis 1]",
+		"COMMIT",
+	}, d.log)
+}
+
+func TestSinkRollback(t *testing.T) {
+	db, d := openFake(t, "Lasiurus")
+	sink := New(context.Background(), db, Options{
+		Table:           "sightings",
+		Columns:         []string{"species", "count"},
+		MaxPlaceholders: 4,
+		Transaction:     true,
+	}, sightingRow)
+
+	err := sahil.Drain(sahil.Slice(sightings), sink, 5)
+	assert.EqualError(t, err, "CONSTRAINT VIOLATION")
+	assert.Equal(t, "BEGIN", d.log[0])
+	assert.Equal(t, "ROLLBACK", d.log[len(d.log)-1])
+}
+
+func TestSinkRowErr(t *testing.T) {
+	db, _ := openFake(t, "")
+	sink := New(context.Background(), db, Options{
+		Table:   "sightings",
+		Columns: []string{"species", "count", "roost"},
+	}, sightingRow)
+
+	err := sahil.Drain(sahil.Slice(sightings), sink, 5)
+	assert.EqualError(t, err, "row has 2 values for 3 columns")
+}
@@ -0,0 +1,94 @@
+package sahil
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// topKBatchSize is the smallest batch TopK will fetch at a time.
+const topKBatchSize = 1024
+
+// TopK consumes a Paginated and returns its k smallest elements according to
+// less, sorted from smallest to largest. (To get the k largest, flip less.)
+//
+// TopK only ever keeps k elements in memory, plus whatever batch it is
+// currently looking at, so it is suitable for picking the best few rows out of
+// a very large source. It reads the whole source in large batches.
+//
+// Elements that compare equal keep the order they arrived in, and when there
+// is a tie at the cutoff the ones that arrived first are kept.
+//
+// If the Paginated has fewer than k elements, all of them are returned.
+func TopK[T any](p Paginated[T], k int, less func(a, b T) bool) ([]T, error) {
+	if k < 1 {
+		return nil, nil
+	}
+
+	batchSize := k
+	if batchSize < topKBatchSize {
+		batchSize = topKBatchSize
+	}
+
+	// a max-heap, so the worst of the current top k is always on top; ties are
+	// broken by arrival order, so a later arrival is worse than an earlier one
+	h := &topKHeap[T]{less: less}
+	index := 0
+	for {
+		batch, err := p.Fetch(batchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range batch {
+			if h.Len() < k {
+				heap.Push(h, topKElement[T]{t, index})
+			} else if less(t, h.elements[0].t) {
+				h.elements[0] = topKElement[T]{t, index}
+				heap.Fix(h, 0)
+			}
+			index++
+		}
+
+		if len(batch) < batchSize {
+			break
+		}
+	}
+
+	sort.Slice(h.elements, func(i, j int) bool { return h.before(h.elements[i], h.elements[j]) })
+	out := make([]T, len(h.elements))
+	for i, e := range h.elements {
+		out[i] = e.t
+	}
+	return out, nil
+}
+
+type topKElement[T any] struct {
+	t     T
+	index int
+}
+
+type topKHeap[T any] struct {
+	elements []topKElement[T]
+	less     func(a, b T) bool
+}
+
+// before orders by less, then by arrival.
+func (h *topKHeap[T]) before(a, b topKElement[T]) bool {
+	if h.less(a.t, b.t) {
+		return true
+	}
+	if h.less(b.t, a.t) {
+		return false
+	}
+	return a.index < b.index
+}
+
+func (h *topKHeap[T]) Len() int           { return len(h.elements) }
+func (h *topKHeap[T]) Less(i, j int) bool { return h.before(h.elements[j], h.elements[i]) }
+func (h *topKHeap[T]) Swap(i, j int)      { h.elements[i], h.elements[j] = h.elements[j], h.elements[i] }
+func (h *topKHeap[T]) Push(x any)         { h.elements = append(h.elements, x.(topKElement[T])) }
+func (h *topKHeap[T]) Pop() any {
+	last := h.elements[len(h.elements)-1]
+	h.elements = h.elements[:len(h.elements)-1]
+	return last
+}
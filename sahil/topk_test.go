@@ -0,0 +1,52 @@
+package sahil
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopK(t *testing.T) {
+	var input []int
+	for i := 0; i < 5000; i++ {
+		input = append(input, (i*7919)%5000)
+	}
+
+	results, err := TopK(Slice(input), 5, func(a, b int) bool { return a > b })
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{4999, 4998, 4997, 4996, 4995}, results)
+}
+
+func TestTopKShort(t *testing.T) {
+	results, err := TopK(Slice([]int{3, 1, 2}), 5, func(a, b int) bool { return a < b })
+	assert.Nil(t, err)
+	assert.EqualValues(t, []int{1, 2, 3}, results)
+}
+
+func TestTopKErr(t *testing.T) {
+	i := 0
+	src := Func(func() (int, error) {
+		i += 1
+		if i > 2000 {
+			return 0, errors.New("HEAP ERROR")
+		}
+		return i, nil
+	})
+
+	results, err := TopK(src, 5, func(a, b int) bool { return a < b })
+	assert.Nil(t, results)
+	assert.EqualError(t, err, "HEAP ERROR")
+}
+
+func TestTopKTies(t *testing.T) {
+	type row struct{ key, id int }
+	var input []row
+	for i := 0; i < 3000; i++ {
+		input = append(input, row{i % 3, i})
+	}
+
+	results, err := TopK(Slice(input), 4, func(a, b row) bool { return a.key < b.key })
+	assert.Nil(t, err)
+	assert.EqualValues(t, []row{{0, 0}, {0, 3}, {0, 6}, {0, 9}}, results)
+}
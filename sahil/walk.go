@@ -0,0 +1,146 @@
+package sahil
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// WalkEntry is a file or directory found by WalkDir.
+type WalkEntry struct {
+	Path  string // path of the entry, including the root
+	Entry fs.DirEntry
+}
+
+// WalkOptions filters the entries produced by WalkDir. The zero value
+// produces every entry, like fs.WalkDir.
+type WalkOptions struct {
+	// Include, if set, restricts the output to entries matching at least one
+	// of these patterns. Directories that don't match are still walked.
+	Include []string
+
+	// Exclude drops entries matching any of these patterns. A directory that
+	// matches is not walked at all.
+	Exclude []string
+
+	// SkipDir, if set, is called on each directory before it is walked. If
+	// it returns true, the directory is still produced (subject to Include
+	// and Exclude), but its contents are not.
+	SkipDir func(path string, d fs.DirEntry) bool
+}
+
+type walkDir struct {
+	fsys    fs.FS
+	root    string
+	opts    WalkOptions
+	pending []WalkEntry // unvisited entries of each directory on the current path, last first
+	started bool
+}
+
+// WalkDir creates a Paginated of the files and directories under root in
+// fsys, in the same order as fs.WalkDir: depth first, each directory before
+// its contents, and the contents of each directory in lexical order.
+//
+// Unlike fs.WalkDir, the walk happens lazily, one directory at a time, as
+// elements are fetched. What is held in memory is the unvisited entries of
+// each directory on the current path, files included, rather than the whole
+// tree.
+//
+// Patterns in opts use the syntax of path.Match. A pattern containing a slash
+// is matched against the entry's whole path. Otherwise, it is matched against
+// the entry's name, so "*.log" finds log files at any depth. A bad pattern
+// produces path.ErrBadPattern.
+//
+// An error reading a directory (or root itself) is propagated to the Fetch
+// caller.
+func WalkDir(fsys fs.FS, root string, opts WalkOptions) Paginated[WalkEntry] {
+	return wrap[WalkEntry](&walkDir{fsys: fsys, root: root, opts: opts})
+}
+
+func (w *walkDir) Fetch(atLeast int) ([]WalkEntry, error) {
+	if !w.started {
+		if err := w.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	var out []WalkEntry
+	for len(out) < atLeast && len(w.pending) > 0 {
+		entry := w.pending[len(w.pending)-1]
+		w.pending = w.pending[:len(w.pending)-1]
+
+		excluded, err := matchAny(w.opts.Exclude, entry.Path)
+		if err != nil {
+			return nil, err
+		}
+		if excluded {
+			continue
+		}
+
+		included := true
+		if len(w.opts.Include) > 0 {
+			included, err = matchAny(w.opts.Include, entry.Path)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if included {
+			out = append(out, entry)
+		}
+
+		if entry.Entry.IsDir() && (w.opts.SkipDir == nil || !w.opts.SkipDir(entry.Path, entry.Entry)) {
+			children, err := fs.ReadDir(w.fsys, entry.Path)
+			if err != nil {
+				return nil, err
+			}
+			// push in reverse so the first child is visited next
+			for i := len(children) - 1; i >= 0; i-- {
+				w.pending = append(w.pending, WalkEntry{
+					Path:  path.Join(entry.Path, children[i].Name()),
+					Entry: children[i],
+				})
+			}
+		}
+	}
+	return out, nil
+}
+
+func (w *walkDir) start() error {
+	// check the patterns up front, so that a bad one fails even if nothing
+	// would be tested against it
+	for _, pattern := range append(append([]string{}, w.opts.Include...), w.opts.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return err
+		}
+	}
+
+	info, err := fs.Stat(w.fsys, w.root)
+	if err != nil {
+		return err
+	}
+	w.pending = []WalkEntry{{Path: w.root, Entry: fs.FileInfoToDirEntry(info)}}
+	w.started = true
+	return nil
+}
+
+func (w *walkDir) Close() error {
+	w.pending = nil
+	return nil
+}
+
+func matchAny(patterns []string, p string) (bool, error) {
+	for _, pattern := range patterns {
+		target := p
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(p)
+		}
+		ok, err := path.Match(pattern, target)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,102 @@
+package sahil
+
+import (
+	"io/fs"
+	"path"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var roosts = fstest.MapFS{
+	"caves/bracken/census.log":    {Data: []byte("20000000")},
+	"caves/bracken/notes.txt":     {Data: []byte("Tadarida brasiliensis")},
+	"caves/carlsbad/census.log":   {Data: []byte("400000")},
+	"bridges/congress/census.log": {Data: []byte("1500000")},
+	"bridges/README":              {Data: []byte("urban roosts")},
+}
+
+func walkPaths(t *testing.T, p Paginated[WalkEntry]) []string {
+	var paths []string
+	for {
+		batch, err := p.Fetch(2)
+		assert.Nil(t, err)
+		for _, e := range batch {
+			paths = append(paths, e.Path)
+		}
+		if p.Exhausted() {
+			return paths
+		}
+	}
+}
+
+func TestWalkDir(t *testing.T) {
+	var expected []string
+	err := fs.WalkDir(roosts, ".", func(p string, d fs.DirEntry, err error) error {
+		expected = append(expected, p)
+		return err
+	})
+	assert.Nil(t, err)
+
+	assert.EqualValues(t, expected, walkPaths(t, WalkDir(roosts, ".", WalkOptions{})))
+}
+
+func TestWalkDirSubtree(t *testing.T) {
+	assert.EqualValues(t, []string{
+		"caves/bracken",
+		"caves/bracken/census.log",
+		"caves/bracken/notes.txt",
+	}, walkPaths(t, WalkDir(roosts, "caves/bracken", WalkOptions{})))
+}
+
+func TestWalkDirFilters(t *testing.T) {
+	assert.EqualValues(t, []string{
+		"bridges/congress/census.log",
+		"caves/bracken/census.log",
+	}, walkPaths(t, WalkDir(roosts, ".", WalkOptions{
+		Include: []string{"*.log"},
+		Exclude: []string{"caves/carlsbad"},
+	})))
+
+	assert.EqualValues(t, []string{
+		".",
+		"bridges",
+		"bridges/README",
+		"bridges/congress",
+		"caves",
+	}, walkPaths(t, WalkDir(roosts, ".", WalkOptions{
+		Include: []string{"*"},
+		SkipDir: func(p string, d fs.DirEntry) bool {
+			return path.Dir(p) != "." || p == "caves"
+		},
+	})))
+}
+
+func TestWalkDirLazy(t *testing.T) {
+	fsys := &countingFS{MapFS: roosts}
+	src := WalkDir(fsys, ".", WalkOptions{})
+
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, 2, fsys.reads)
+}
+
+func TestWalkDirErr(t *testing.T) {
+	_, err := WalkDir(roosts, "attic", WalkOptions{}).Fetch(1)
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = WalkDir(roosts, ".", WalkOptions{Include: []string{"["}}).Fetch(1)
+	assert.ErrorIs(t, err, path.ErrBadPattern)
+}
+
+type countingFS struct {
+	fstest.MapFS
+	reads int
+}
+
+func (c *countingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	c.reads += 1
+	return c.MapFS.ReadDir(name)
+}
@@ -0,0 +1,96 @@
+package sahil
+
+type window[T any] struct {
+	underlying Paginated[T]
+	size, step int
+	pending    []T // elements from the start of the next window onward
+	skip       int // elements to drop before the next window starts
+}
+
+// Window produces sliding windows of `size` elements over a Paginated, with
+// each window starting `step` elements after the previous one.
+//
+// If step is less than size, windows overlap. If step is greater than size,
+// the elements between windows are dropped. Only full windows are produced,
+// so a Paginated with fewer than `size` elements produces none.
+//
+// Each window is a fresh slice that the caller may keep or modify.
+//
+// Window fetches from the underlying Paginated in batches large enough for all
+// of the windows it was asked for, rather than once per window.
+func Window[T any](p Paginated[T], size int, step int) Paginated[[]T] {
+	if size < 1 {
+		size = 1
+	}
+	if step < 1 {
+		step = 1
+	}
+	return wrap[[]T](&window[T]{
+		underlying: p,
+		size:       size,
+		step:       step,
+	})
+}
+
+func (w *window[T]) Fetch(atLeast int) ([][]T, error) {
+	var out [][]T
+
+	for len(out) < atLeast && !w.underlying.Exhausted() {
+		// enough elements for every window we still owe
+		nWanted := w.skip + (atLeast-len(out)-1)*w.step + w.size - len(w.pending)
+		input, err := w.underlying.Fetch(nWanted)
+		if err != nil {
+			return nil, err
+		}
+
+		if w.skip > len(input) {
+			w.skip -= len(input)
+			continue
+		}
+		input = input[w.skip:]
+		w.skip = 0
+		w.pending = append(w.pending, input...)
+
+		for len(w.pending) >= w.size {
+			next := make([]T, w.size)
+			copy(next, w.pending)
+			out = append(out, next)
+
+			if w.step > len(w.pending) {
+				w.skip = w.step - len(w.pending)
+				w.pending = nil
+			} else {
+				w.pending = w.pending[w.step:]
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func (w *window[T]) Close() error {
+	return w.underlying.Close()
+}
+
+type windowCheckpoint[T any] struct {
+	Pending    []T
+	Skip       int
+	Underlying []byte
+}
+
+func (w *window[T]) Checkpoint() ([]byte, error) {
+	underlying, err := w.underlying.Checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	return encodeCheckpoint(windowCheckpoint[T]{Pending: w.pending, Skip: w.skip, Underlying: underlying})
+}
+
+func (w *window[T]) Restore(checkpoint []byte) error {
+	var saved windowCheckpoint[T]
+	if err := decodeCheckpoint(checkpoint, &saved); err != nil {
+		return err
+	}
+	w.pending, w.skip = saved.Pending, saved.Skip
+	return w.underlying.restore(saved.Underlying)
+}
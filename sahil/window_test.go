@@ -0,0 +1,50 @@
+package sahil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindow(t *testing.T) {
+	src := Window(Slice([]int{1, 2, 3, 4, 5, 6}), 3, 1)
+
+	results, err := src.Fetch(2)
+	assert.Nil(t, err)
+	assert.EqualValues(t, [][]int{{1, 2, 3}, {2, 3, 4}}, results)
+
+	results, err = src.Fetch(3)
+	assert.Nil(t, err)
+	assert.EqualValues(t, [][]int{{3, 4, 5}, {4, 5, 6}}, results)
+
+	results, err = src.Fetch(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+}
+
+func TestWindowStep(t *testing.T) {
+	src := Window(Slice([]int{1, 2, 3, 4, 5, 6, 7, 8, 9}), 2, 3)
+
+	results, err := src.Fetch(5)
+	assert.Nil(t, err)
+	assert.EqualValues(t, [][]int{{1, 2}, {4, 5}, {7, 8}}, results)
+}
+
+func TestWindowShort(t *testing.T) {
+	src := Window(Slice([]int{1, 2}), 3, 1)
+
+	results, err := src.Fetch(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+}
+
+func TestWindowIndependent(t *testing.T) {
+	src := Window(Slice([]int{1, 2, 3, 4}), 2, 1)
+
+	results, err := src.Fetch(3)
+	assert.Nil(t, err)
+
+	// modifying one window shouldn't affect its neighbors
+	results[0][1] = 100
+	assert.EqualValues(t, [][]int{{1, 100}, {2, 3}, {3, 4}}, results)
+}